@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/HankelBao/Pseudo/internal/bytecode"
+	"github.com/HankelBao/Pseudo/internal/compiler"
+	"github.com/HankelBao/Pseudo/internal/interpreter"
+	"github.com/alecthomas/repr"
+)
+
+// emitExtensions are the extensions of the outputs of each kind.
+var emitExtensions = map[string]string{
+	"exe": "",
+	"ast": ".ast",
+	"ir":  ".ll",
+	"asm": ".s",
+	"obj": ".o",
+	"pbc": ".pbc",
+}
+
+// build compiles a file into an executable or what is asked by --emit.
+// It gives the exit code.
+//
+// Usage:
+// 	pseudo build [-o output] [--emit=exe|ast|ir|asm|obj|pbc] file.pse
+func build(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	output := flags.String("o", "", "output file, derived from the input if not given")
+	emit := flags.String("emit", "exe", "kind of output: exe, ast, ir, asm, obj or pbc")
+	flags.Parse(args)
+	ext, ok := emitExtensions[*emit]
+	if flags.NArg() != 1 || !ok {
+		fmt.Fprintln(os.Stderr, "Usage: pseudo build [-o output] [--emit=exe|ast|ir|asm|obj|pbc] file.pse")
+		return 2
+	}
+	src, err := readSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 2
+	}
+	if *output == "" {
+		*output = outputName(src.Filename, ext)
+	}
+
+	if *emit == "ast" {
+		ast, err := src.parse()
+		if err != nil {
+			src.report(err)
+			return 1
+		}
+		if err := writeOutput(*output, repr.String(ast, repr.Indent("  "))+"\n"); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+	if *emit == "pbc" {
+		program, err := src.compileBytecode()
+		if err != nil {
+			src.report(err)
+			return 1
+		}
+		var content bytes.Buffer
+		bytecode.Encode(&content, program)
+		if err := writeOutput(*output, content.String()); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	ir, err := src.compile()
+	if err != nil {
+		src.report(err)
+		return 1
+	}
+	if err := link(ir, *emit, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// link writes the IR into the output of the kind.
+// clang is used for anything other than IR.
+// Text outputs could be written to stdout with "-".
+// The IR and the embedded runtime are written into a private temporary directory,
+// which is removed after linking, so that pseudo works from any directory.
+// The runtime is linked into executables only,
+// so objects should be linked with internal/compiler/runtime.c.
+func link(ir string, emit string, output string) error {
+	if emit == "ir" {
+		return writeOutput(output, ir)
+	}
+
+	dir, err := ioutil.TempDir("", "pseudo")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	irFile := filepath.Join(dir, "program.ll")
+	if err := ioutil.WriteFile(irFile, []byte(ir), 0644); err != nil {
+		return err
+	}
+
+	clangArgs := []string{irFile}
+	switch emit {
+	case "asm":
+		clangArgs = append(clangArgs, "-S")
+	case "obj":
+		clangArgs = append(clangArgs, "-c")
+	case "exe":
+		runtimeFile := filepath.Join(dir, "runtime.c")
+		if err := ioutil.WriteFile(runtimeFile, []byte(compiler.RuntimeSource), 0644); err != nil {
+			return err
+		}
+		clangArgs = append(clangArgs, runtimeFile)
+	}
+	clangArgs = append(clangArgs, "-o", output)
+	clangCmd := exec.Command("clang", clangArgs...)
+	clangCmd.Stdout = os.Stderr
+	clangCmd.Stderr = os.Stderr
+	if err := clangCmd.Run(); err != nil {
+		return fmt.Errorf("clang: %s", err)
+	}
+	return nil
+}
+
+// writeOutput writes the text output into the file, or stdout if it is "-".
+func writeOutput(output string, content string) error {
+	if output == "-" {
+		_, err := os.Stdout.WriteString(content)
+		return err
+	}
+	return ioutil.WriteFile(output, []byte(content), 0644)
+}
+
+// run builds a file and runs it with the arguments.
+// With --interp, it is run by the interpreter without clang.
+// With --vm, it is compiled into bytecode and run by the VM,
+// and files of bytecode built by --emit=pbc could be run directly.
+// It gives the exit code of the program.
+//
+// Usage:
+// 	pseudo run [--interp | --vm [--limit=n]] file.pse [args...]
+func run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	interp := flags.Bool("interp", false, "run with the interpreter instead of building")
+	vm := flags.Bool("vm", false, "run with the bytecode VM instead of building")
+	limit := flags.Int64("limit", 0, "maximum number of instructions run by the VM, 0 for no limit")
+	flags.Parse(args)
+	if flags.NArg() < 1 || *interp && *vm {
+		fmt.Fprintln(os.Stderr, "Usage: pseudo run [--interp | --vm [--limit=n]] file.pse [args...]")
+		return 2
+	}
+	src, err := readSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 2
+	}
+	if *interp {
+		return interpret(src)
+	}
+	if *vm {
+		return runBytecode(src, *limit)
+	}
+
+	ir, err := src.compile()
+	if err != nil {
+		src.report(err)
+		return 1
+	}
+	// The executable is built into a temporary directory, which is removed after it is run.
+	dir, err := ioutil.TempDir("", "pseudo")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	executable := filepath.Join(dir, filepath.Base(outputName(src.Filename, "")))
+	if err := link(ir, "exe", executable); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+
+	programCmd := exec.Command(executable, flags.Args()[1:]...)
+	programCmd.Stdin = os.Stdin
+	programCmd.Stdout = os.Stdout
+	programCmd.Stderr = os.Stderr
+	if err := programCmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// interpret runs the source with the interpreter.
+// It gives the exit code of the program.
+func interpret(src *source) int {
+	ast, err := src.parse()
+	if err != nil {
+		src.report(err)
+		return 1
+	}
+	code, err := interpreter.New(os.Stdin, os.Stdout, os.Stderr).Run(ast)
+	if err != nil {
+		src.locate(err)
+		src.report(err)
+		return 1
+	}
+	return code
+}
+
+// runBytecode runs the source, or the bytecode if it is a .pbc file, with the VM.
+// It gives the exit code of the program.
+func runBytecode(src *source, limit int64) int {
+	var program *bytecode.Program
+	var err error
+	if strings.EqualFold(filepath.Ext(src.Filename), ".pbc") {
+		program, err = bytecode.Decode(bytes.NewReader(src.Content))
+	} else {
+		program, err = src.compileBytecode()
+	}
+	if err != nil {
+		src.report(err)
+		return 1
+	}
+	vm := bytecode.NewVM(program, os.Stdin, os.Stdout, os.Stderr)
+	vm.Limit = limit
+	code, err := vm.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return code
+}
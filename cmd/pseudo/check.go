@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// check reports all the errors of a file without building it.
+// It gives the exit code, which is 1 if there are any errors.
+//
+// Usage:
+// 	pseudo check [--format=text|json] file.pse
+func check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	format := flags.String("format", "text", "output format of diagnostics: text or json")
+	flags.Parse(args)
+	if flags.NArg() != 1 || (*format != "text" && *format != "json") {
+		fmt.Fprintln(os.Stderr, "Usage: pseudo check [--format=text|json] file.pse")
+		return 2
+	}
+	src, err := readSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 2
+	}
+
+	_, err = src.compile()
+	diagnostics, ok := compiler.AsDiagnostics(err)
+	if err != nil && !ok {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 2
+	}
+
+	if *format == "json" {
+		if diagnostics == nil {
+			// Tools expect an array even if there are no errors.
+			diagnostics = compiler.Diagnostics{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(diagnostics)
+	} else if len(diagnostics) != 0 {
+		diagnostics.Print(os.Stderr, src.Content)
+	}
+	if len(diagnostics) != 0 {
+		return 1
+	}
+	return 0
+}
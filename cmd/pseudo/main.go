@@ -1,54 +1,120 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"io/ioutil"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 
+	"github.com/HankelBao/Pseudo/internal/bytecode"
 	"github.com/HankelBao/Pseudo/internal/compiler"
-	"github.com/alecthomas/repr"
 )
 
+const usage = `Usage:
+	pseudo build [-o output] [--emit=exe|ast|ir|asm|obj|pbc] file.pse
+	pseudo run [--interp | --vm [--limit=n]] file.pse [args...]
+	pseudo check [--format=text|json] file.pse`
+
 func main() {
 	if len(os.Args) < 2 {
-		log.Fatal("Filename required")
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
 	}
-	filename := os.Args[1]
-	in, err := os.Open(filename)
-	if err != nil {
-		log.Fatal("Could not open file")
+	var code int
+	switch os.Args[1] {
+	case "build":
+		code = build(os.Args[2:])
+	case "run":
+		code = run(os.Args[2:])
+	case "check":
+		code = check(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %s\n%s\n", os.Args[1], usage)
+		code = 2
 	}
+	os.Exit(code)
+}
 
-	ast := compiler.Parse(in)
-	repr.Println(ast)
+// source is a pseudocode file to be compiled.
+type source struct {
+	Filename string
+	Content  []byte
+}
 
-	m := compiler.Compile(ast)
-	// fmt.Println(m)
+// readSource reads the pseudocode file.
+func readSource(filename string) (*source, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &source{Filename: filename, Content: content}, nil
+}
 
-	os.MkdirAll("./tmp", os.ModePerm)
-	out, _ := os.Create("./tmp/test.ll")
-	out.Write([]byte(m.String()))
-	out.Close()
+// parse parses the source into an ast.
+func (s *source) parse() (*compiler.Ast, error) {
+	ast, err := compiler.Parse(strings.NewReader(string(s.Content)))
+	s.locate(err)
+	return ast, err
+}
 
-	var targetName string
-	if runtime.GOOS == "windows" {
-		targetName = "./tmp/test.exe"
-	} else if runtime.GOOS == "linux" {
-		targetName = "./tmp/test"
+// compile parses and compiles the source into a module of LLVM IR.
+func (s *source) compile() (string, error) {
+	ast, err := s.parse()
+	if err != nil {
+		return "", err
+	}
+	module, err := compiler.Compile(ast)
+	if err != nil {
+		s.locate(err)
+		return "", err
 	}
+	return module.String(), nil
+}
 
-	clangCmdOutput, clangCmdErr := exec.Command("clang", "./tmp/test.ll", "./runtime.c", "-o", targetName).Output()
-	if clangCmdErr != nil {
-		log.Fatal(clangCmdErr)
+// compileBytecode parses and compiles the source into bytecode.
+func (s *source) compileBytecode() (*bytecode.Program, error) {
+	ast, err := s.parse()
+	if err != nil {
+		return nil, err
 	}
-	if string(clangCmdOutput) != "" {
-		log.Println(string(clangCmdOutput))
+	program, err := bytecode.Compile(ast)
+	if err != nil {
+		s.locate(err)
+		return nil, err
+	}
+	return program, nil
+}
+
+// locate sets the file of the diagnostics in the error,
+// since the parser reads the source without its name.
+func (s *source) locate(err error) {
+	diagnostics, _ := compiler.AsDiagnostics(err)
+	for _, diagnostic := range diagnostics {
+		if diagnostic.File == "" {
+			diagnostic.File = s.Filename
+		}
 	}
+}
 
-	/*execCmdOutput, execCmdErr := exec.Command(targetName).Output()
-	if execCmdErr != nil {
-		log.Fatal(execCmdErr)
+// report prints the error with the source.
+func (s *source) report(err error) {
+	compiler.PrintError(os.Stderr, err, s.Content)
+}
+
+// outputName derives the name of the output from the name of the input.
+// The extension is replaced by ext, or removed for executables if ext is empty.
+func outputName(input string, ext string) string {
+	if ext == "" && runtime.GOOS == "windows" {
+		ext = ".exe"
+	}
+	output := strings.TrimSuffix(input, filepath.Ext(input)) + ext
+	if output == input {
+		// The input should never be overwritten.
+		output += ".out"
 	}
-	fmt.Println(string(execCmdOutput))*/
+	return output
 }
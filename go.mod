@@ -1,6 +1,6 @@
 module github.com/HankelBao/Pseudo
 
-go 1.12
+go 1.16
 
 require (
 	github.com/alecthomas/participle v0.2.1
@@ -0,0 +1,540 @@
+package bytecode
+
+import (
+	"github.com/HankelBao/Pseudo/internal/compiler"
+	"github.com/HankelBao/Pseudo/internal/interpreter"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Compile compiles the ast into bytecode.
+// The ast is checked first, so that the same programs as the compiler are accepted,
+// and all the errors are given as Diagnostics.
+// Variables are resolved into slots here, while types are left to the VM,
+// which follows the interpreter.
+func Compile(ast *compiler.Ast) (*Program, error) {
+	if diagnostics := compiler.Check(ast); len(diagnostics) != 0 {
+		return nil, diagnostics
+	}
+	g := &generator{
+		program:   &Program{},
+		functions: make(map[string]int),
+		natives:   make(map[string]int),
+		constants: make(map[interpreter.Value]int),
+		types:     make(map[*compiler.VariableType]int),
+	}
+	g.declare(ast)
+	return g.program, nil
+}
+
+// generator generates the bytecode of a program.
+type generator struct {
+	program *Program
+	// function is the function being generated.
+	function *Function
+	scope    *scope
+
+	functions map[string]int
+	natives   map[string]int
+	constants map[interpreter.Value]int
+	types     map[*compiler.VariableType]int
+}
+
+// scope maps names to variables, like interpreter.Scope.
+type scope struct {
+	variables map[string]variable
+	parent    *scope
+}
+
+// variable is a slot of a global or local variable.
+type variable struct {
+	global bool
+	slot   int
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{variables: make(map[string]variable), parent: parent}
+}
+
+// find finds the variable in the scope and its parents.
+func (s *scope) find(name string) (variable, bool) {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.variables[name]; ok {
+			return v, true
+		}
+	}
+	return variable{}, false
+}
+
+// declare registers types, subroutines and global variables, and generates the functions.
+// It follows Interpreter.declare: all of them could be used before they are defined.
+func (g *generator) declare(ast *compiler.Ast) {
+	globalScope := newScope(nil)
+	main := &Function{Name: "main", Return: -1}
+	g.program.Functions = append(g.program.Functions, main)
+
+	type subroutine struct {
+		params []*compiler.Parameter
+		body   *compiler.Ast
+		pos    lexer.Position
+	}
+	var subroutines []subroutine
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.TypeDefinition != nil:
+			g.program.Records = append(g.program.Records, inst.TypeDefinition)
+		case inst.Procedure != nil:
+			ins := inst.Procedure
+			g.functions[ins.Name] = g.addFunction(ins.Name, ins.Params, -1)
+			subroutines = append(subroutines, subroutine{ins.Params, &ins.Body, ins.Pos})
+		case inst.Function != nil:
+			ins := inst.Function
+			g.functions[ins.Name] = g.addFunction(ins.Name, ins.Params, g.typeIndex(&ins.ReturnType))
+			subroutines = append(subroutines, subroutine{ins.Params, &ins.Body, ins.Pos})
+		case inst.DeclareVariable != nil:
+			ins := inst.DeclareVariable
+			globalScope.variables[ins.Name] = variable{global: true, slot: len(g.program.Globals)}
+			g.program.Globals = append(g.program.Globals, g.typeIndex(&ins.Type))
+		}
+	}
+
+	// The main block is in the global scope, so its declarations are the global variables.
+	g.function, g.scope = main, globalScope
+	g.instructions(ast)
+	g.emit(OpReturn, 0, lexer.Position{})
+
+	for index, sub := range subroutines {
+		g.function = g.program.Functions[index+1]
+		g.scope = newScope(globalScope)
+		for slot, param := range sub.params {
+			g.scope.variables[param.Name] = variable{slot: slot}
+		}
+		g.block(sub.body)
+		g.emit(OpReturn, 0, sub.pos)
+	}
+}
+
+// addFunction adds a subroutine with its parameters as the first locals.
+func (g *generator) addFunction(name string, params []*compiler.Parameter, returnType int) int {
+	function := &Function{Name: name, Return: returnType}
+	for _, param := range params {
+		t := g.typeIndex(&param.Type)
+		function.Params = append(function.Params, &Param{BYREF: param.BYREF, Type: t})
+		function.Locals = append(function.Locals, t)
+	}
+	g.program.Functions = append(g.program.Functions, function)
+	return len(g.program.Functions) - 1
+}
+
+// emit appends an instruction to the function, and gives its index.
+func (g *generator) emit(op Opcode, arg int, pos lexer.Position) int {
+	g.function.Code = append(g.function.Code, Instruction{Op: op, Arg: int32(arg), Line: int32(pos.Line)})
+	return len(g.function.Code) - 1
+}
+
+// label gives the index of the next instruction, which jumps could go to.
+func (g *generator) label() int {
+	return len(g.function.Code)
+}
+
+// patch makes the jump at index go to the label.
+func (g *generator) patch(index int, label int) {
+	g.function.Code[index].Arg = int32(label)
+}
+
+// constant gives the index of the value in the constant pool.
+func (g *generator) constant(val interpreter.Value) int {
+	if index, ok := g.constants[val]; ok {
+		return index
+	}
+	g.program.Constants = append(g.program.Constants, val)
+	g.constants[val] = len(g.program.Constants) - 1
+	return len(g.program.Constants) - 1
+}
+
+// typeIndex gives the index of the type.
+func (g *generator) typeIndex(t *compiler.VariableType) int {
+	if index, ok := g.types[t]; ok {
+		return index
+	}
+	g.program.Types = append(g.program.Types, t)
+	g.types[t] = len(g.program.Types) - 1
+	return len(g.program.Types) - 1
+}
+
+// native gives the index of the built-in or runtime function.
+func (g *generator) native(name string, arity int) int {
+	if index, ok := g.natives[name]; ok && g.program.Natives[index].Arity == arity {
+		return index
+	}
+	g.program.Natives = append(g.program.Natives, &Native{Name: name, Arity: arity})
+	g.natives[name] = len(g.program.Natives) - 1
+	return len(g.program.Natives) - 1
+}
+
+// local adds a local variable to the function.
+func (g *generator) local(t int) int {
+	g.function.Locals = append(g.function.Locals, t)
+	return len(g.function.Locals) - 1
+}
+
+// block generates the instructions in a new scope.
+func (g *generator) block(ast *compiler.Ast) {
+	outer := g.scope
+	g.scope = newScope(outer)
+	g.instructions(ast)
+	g.scope = outer
+}
+
+// instructions generates the instructions in the current scope.
+// It follows Interpreter.runAst.
+func (g *generator) instructions(ast *compiler.Ast) {
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.Output != nil:
+			for _, item := range inst.Output.Items {
+				g.expression(item)
+				g.emit(OpOutput, 0, inst.Output.Pos)
+			}
+			g.emit(OpNewline, 0, inst.Output.Pos)
+		case inst.Input != nil:
+			g.key(&inst.Input.Content)
+			g.emit(OpInput, 0, inst.Input.Pos)
+		case inst.Call != nil:
+			ins := inst.Call
+			if ins.Name != nil {
+				g.call(&compiler.FunctionCall{Pos: ins.Pos, Name: *ins.Name})
+			} else {
+				g.call(ins.Function)
+			}
+			g.emit(OpPop, 0, ins.Pos)
+		case inst.DeclareVariable != nil:
+			g.declareVariable(inst.DeclareVariable)
+		case inst.TypeDefinition != nil, inst.Procedure != nil, inst.Function != nil:
+			// They have been handled by declare.
+		case inst.Return != nil:
+			g.expression(&inst.Return.Value)
+			g.emit(OpReturn, 1, inst.Return.Pos)
+		case inst.Assignment != nil:
+			g.key(&inst.Assignment.Left)
+			g.expression(&inst.Assignment.Right)
+			g.emit(OpStore, 0, inst.Assignment.Pos)
+		case inst.ConditionBr != nil:
+			g.conditionBr(inst.ConditionBr)
+		case inst.While != nil:
+			g.while(inst.While)
+		case inst.Repeat != nil:
+			g.repeat(inst.Repeat)
+		case inst.For != nil:
+			g.forLoop(inst.For)
+		case inst.Case != nil:
+			g.caseOf(inst.Case)
+		case inst.NullLine != nil:
+		default:
+			panic("unknown instruction")
+		}
+	}
+}
+
+// declareVariable creates a local variable each time the declaration is reached.
+// Global variables have been created by declare.
+func (g *generator) declareVariable(ins *compiler.InstDeclareVariable) {
+	if v, ok := g.scope.variables[ins.Name]; ok && v.global {
+		return
+	}
+	slot := g.local(g.typeIndex(&ins.Type))
+	g.scope.variables[ins.Name] = variable{slot: slot}
+	g.emit(OpInitLocal, slot, ins.Pos)
+}
+
+func (g *generator) conditionBr(ins *compiler.InstConditionBr) {
+	g.expression(&ins.Condition)
+	toFalse := g.emit(OpJumpIfFalse, 0, ins.Pos)
+	g.block(&ins.TrueBr)
+	if ins.FalseBr == nil {
+		g.patch(toFalse, g.label())
+		return
+	}
+	toEnd := g.emit(OpJump, 0, ins.Pos)
+	g.patch(toFalse, g.label())
+	g.block(ins.FalseBr)
+	g.patch(toEnd, g.label())
+}
+
+func (g *generator) while(ins *compiler.InstWhile) {
+	condition := g.label()
+	g.expression(&ins.Condition)
+	toEnd := g.emit(OpJumpIfFalse, 0, ins.Pos)
+	g.block(&ins.Body)
+	g.emit(OpJump, condition, ins.Pos)
+	g.patch(toEnd, g.label())
+}
+
+// repeat generates REPEAT, whose condition is in the scope of the body.
+func (g *generator) repeat(ins *compiler.InstRepeat) {
+	body := g.label()
+	outer := g.scope
+	g.scope = newScope(outer)
+	g.instructions(&ins.Body)
+	g.expression(&ins.Condition)
+	g.scope = outer
+	g.emit(OpJumpIfFalse, body, ins.Pos)
+}
+
+// forLoop generates FOR.
+// The end and the step are kept in two locals, like Interpreter.runFor.
+func (g *generator) forLoop(ins *compiler.InstFor) {
+	counter := &compiler.Variable{Pos: ins.Pos, Name: ins.Counter}
+	kept := g.local(-1)
+	g.local(-1)
+
+	g.variable(counter)
+	g.expression(&ins.Start)
+	g.expression(&ins.End)
+	if ins.Step != nil {
+		g.expression(ins.Step)
+	} else {
+		g.emit(OpConst, g.constant(int32(1)), ins.Pos)
+	}
+	g.emit(OpForPrep, kept, ins.Pos)
+
+	condition := g.label()
+	g.variable(counter)
+	g.emit(OpLoad, 0, ins.Pos)
+	g.emit(OpForTest, kept, ins.Pos)
+	toEnd := g.emit(OpJumpIfFalse, 0, ins.Pos)
+	g.block(&ins.Body)
+	g.variable(counter)
+	g.variable(counter)
+	g.emit(OpLoad, 0, ins.Pos)
+	g.emit(OpLocal, kept+1, ins.Pos)
+	g.emit(OpLoad, 0, ins.Pos)
+	g.emit(OpAdd, 0, ins.Pos)
+	g.emit(OpStore, 0, ins.Pos)
+	g.emit(OpJump, condition, ins.Pos)
+	g.patch(toEnd, g.label())
+}
+
+// caseOf generates CASE.
+// The labels are tested in order, and the bodies follow them.
+func (g *generator) caseOf(ins *compiler.InstCase) {
+	kept := g.local(-1)
+	g.expression(&ins.Value)
+	g.emit(OpSetLocal, kept, ins.Pos)
+
+	toClauses := make([][]int, len(ins.Clauses))
+	for index, clause := range ins.Clauses {
+		for _, label := range clause.Labels {
+			g.emit(OpLocal, kept, label.Pos)
+			g.emit(OpLoad, 0, label.Pos)
+			g.emit(OpConst, g.constant(caseValue(&label.From)), label.Pos)
+			if label.To == nil {
+				g.emit(OpEqual, 0, label.Pos)
+				toClauses[index] = append(toClauses[index], g.emit(OpJumpIfTrue, 0, label.Pos))
+				continue
+			}
+			g.emit(OpGreaterEqual, 0, label.Pos)
+			toNext := g.emit(OpJumpIfFalse, 0, label.Pos)
+			g.emit(OpLocal, kept, label.Pos)
+			g.emit(OpLoad, 0, label.Pos)
+			g.emit(OpConst, g.constant(caseValue(label.To)), label.Pos)
+			g.emit(OpLessEqual, 0, label.Pos)
+			toClauses[index] = append(toClauses[index], g.emit(OpJumpIfTrue, 0, label.Pos))
+			g.patch(toNext, g.label())
+		}
+	}
+
+	var toEnd []int
+	if ins.Otherwise != nil {
+		g.block(ins.Otherwise)
+	}
+	toEnd = append(toEnd, g.emit(OpJump, 0, ins.Pos))
+	for index, clause := range ins.Clauses {
+		for _, jump := range toClauses[index] {
+			g.patch(jump, g.label())
+		}
+		g.block(&clause.Body)
+		toEnd = append(toEnd, g.emit(OpJump, 0, clause.Pos))
+	}
+	for _, jump := range toEnd {
+		g.patch(jump, g.label())
+	}
+}
+
+// caseValue gets the value of the constant in the label.
+func caseValue(v *compiler.CaseValue) interpreter.Value {
+	val := constantValue(&v.Constant)
+	if v.Negative {
+		return interpreter.Opposite(val)
+	}
+	return val
+}
+
+// constantValue gets the value of the constant.
+func constantValue(c *compiler.Constant) interpreter.Value {
+	switch {
+	case c.VString != nil:
+		return *c.VString
+	case c.VChar != nil:
+		// CHARs of more than one byte are rejected by Check.
+		char, _ := c.Char()
+		return char
+	case c.VReal != nil:
+		return *c.VReal
+	case c.VInt != nil:
+		return int32(*c.VInt)
+	case c.VBool != nil:
+		return *c.VBool == "TRUE"
+	}
+	panic("unreachable")
+}
+
+// call generates a call, which leaves a value on the stack.
+// It follows Interpreter.call.
+func (g *generator) call(f *compiler.FunctionCall) {
+	if index, ok := g.functions[f.Name]; ok && !interpreter.IsBuiltin(f.Name) {
+		for i, param := range g.program.Functions[index].Params {
+			if param.BYREF {
+				g.key(f.Params[i].Key())
+			} else {
+				g.expression(f.Params[i])
+			}
+		}
+		g.emit(OpCall, index, f.Pos)
+		return
+	}
+	for _, param := range f.Params {
+		g.expression(param)
+	}
+	g.emit(OpCallNative, g.native(f.Name, len(f.Params)), f.Pos)
+}
+
+// key pushes the reference to the key.
+// It follows Interpreter.locate.
+func (g *generator) key(key *compiler.Key) {
+	g.variable(key.Variables[0])
+	for _, field := range key.Variables[1:] {
+		g.emit(OpField, g.constant(field.Name), field.Pos)
+		g.index(field)
+	}
+}
+
+// variable pushes the reference to the variable with its indices applied.
+func (g *generator) variable(v *compiler.Variable) {
+	found, ok := g.scope.find(v.Name)
+	if !ok {
+		panic("variable not declared: " + v.Name)
+	}
+	if found.global {
+		g.emit(OpGlobal, found.slot, v.Pos)
+	} else {
+		g.emit(OpLocal, found.slot, v.Pos)
+	}
+	g.index(v)
+}
+
+// index applies the indices, and each of them is checked once it is evaluated.
+func (g *generator) index(v *compiler.Variable) {
+	if len(v.Indices) == 0 {
+		return
+	}
+	for i, index := range v.Indices {
+		g.expression(index)
+		g.emit(OpBound, i, v.Pos)
+	}
+	g.emit(OpIndex, len(v.Indices), v.Pos)
+}
+
+// expression generates the expression, which leaves its value on the stack.
+// OR and AND are short-circuited with jumps.
+func (g *generator) expression(e *compiler.Expression) {
+	d := &e.Disjunction
+	g.conjunction(&d.Head)
+	var toEnd []int
+	for _, item := range d.Items {
+		g.emit(OpDup, 0, item.Pos)
+		toEnd = append(toEnd, g.emit(OpJumpIfTrue, 0, item.Pos))
+		g.emit(OpPop, 0, item.Pos)
+		g.conjunction(item)
+	}
+	for _, jump := range toEnd {
+		g.patch(jump, g.label())
+	}
+}
+
+func (g *generator) conjunction(c *compiler.Conjunction) {
+	g.negation(&c.Head)
+	var toEnd []int
+	for _, item := range c.Items {
+		g.emit(OpDup, 0, item.Pos)
+		toEnd = append(toEnd, g.emit(OpJumpIfFalse, 0, item.Pos))
+		g.emit(OpPop, 0, item.Pos)
+		g.negation(item)
+	}
+	for _, jump := range toEnd {
+		g.patch(jump, g.label())
+	}
+}
+
+func (g *generator) negation(n *compiler.Negation) {
+	if n.Not != nil {
+		g.negation(n.Not)
+		g.emit(OpNot, 0, n.Pos)
+		return
+	}
+	c := n.Comparison
+	g.addition(&c.Head)
+	for _, item := range c.Items {
+		g.addition(&item.Item)
+		g.emit(comparisonOpcodes[item.Operator], 0, item.Pos)
+	}
+}
+
+func (g *generator) addition(a *compiler.Addition) {
+	g.multiplication(&a.Head)
+	for _, item := range a.Items {
+		g.multiplication(&item.Item)
+		switch item.Operator {
+		case "+":
+			g.emit(OpAdd, 0, item.Pos)
+		case "-":
+			g.emit(OpMinus, 0, item.Pos)
+		}
+	}
+}
+
+func (g *generator) multiplication(m *compiler.Multiplication) {
+	g.unary(&m.Head)
+	for _, item := range m.Items {
+		g.unary(&item.Item)
+		switch item.Operator {
+		case "*":
+			g.emit(OpMultiple, 0, item.Pos)
+		case "/":
+			g.emit(OpDivide, 0, item.Pos)
+		case "DIV":
+			g.emit(OpIntDivide, 0, item.Pos)
+		case "MOD":
+			g.emit(OpMod, 0, item.Pos)
+		}
+	}
+}
+
+func (g *generator) unary(u *compiler.Unary) {
+	if u.Opposite != nil {
+		g.unary(u.Opposite)
+		g.emit(OpOpposite, 0, u.Pos)
+		return
+	}
+	p := u.Primary
+	switch {
+	case p.Constant != nil:
+		g.emit(OpConst, g.constant(constantValue(p.Constant)), p.Pos)
+	case p.Key != nil:
+		g.key(p.Key)
+		g.emit(OpLoad, 0, p.Pos)
+	case p.Function != nil:
+		g.call(p.Function)
+	case p.Subexpression != nil:
+		g.expression(p.Subexpression)
+	}
+}
@@ -0,0 +1,528 @@
+package bytecode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/HankelBao/Pseudo/internal/compiler"
+	"github.com/HankelBao/Pseudo/internal/interpreter"
+)
+
+// magic starts a .pbc file, and the last byte is the version of the format.
+const magic = "PBC\x01"
+
+// Kinds of constants and types in .pbc files.
+const (
+	kindInt byte = iota
+	kindReal
+	kindBool
+	kindChar
+	kindString
+	kindDate
+	kindArray
+	kindCustom
+)
+
+// Encode writes the program in the format of .pbc files.
+// Integers are written as varints, so that most instructions take three bytes.
+func Encode(w io.Writer, p *Program) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.w.WriteString(magic)
+
+	e.uint(len(p.Constants))
+	for _, constant := range p.Constants {
+		e.constant(constant)
+	}
+	e.uint(len(p.Records))
+	for _, record := range p.Records {
+		e.string(record.Name)
+		e.uint(len(record.Fields))
+		for _, field := range record.Fields {
+			e.string(field.Name)
+			e.variableType(&field.Type)
+		}
+	}
+	e.uint(len(p.Types))
+	for _, t := range p.Types {
+		e.variableType(t)
+	}
+	e.ints(p.Globals)
+	e.uint(len(p.Natives))
+	for _, native := range p.Natives {
+		e.string(native.Name)
+		e.uint(native.Arity)
+	}
+	e.uint(len(p.Functions))
+	for _, function := range p.Functions {
+		e.string(function.Name)
+		e.uint(len(function.Params))
+		for _, param := range function.Params {
+			e.bool(param.BYREF)
+			e.int(int64(param.Type))
+		}
+		e.int(int64(function.Return))
+		e.ints(function.Locals)
+		e.uint(len(function.Code))
+		line := int32(0)
+		for _, inst := range function.Code {
+			e.w.WriteByte(byte(inst.Op))
+			e.int(int64(inst.Arg))
+			e.int(int64(inst.Line - line))
+			line = inst.Line
+		}
+	}
+	return e.w.Flush()
+}
+
+// encoder writes the parts of a .pbc file.
+// Errors are kept by the bufio.Writer and given by Flush.
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uint(i int) {
+	n := binary.PutUvarint(e.buf[:], uint64(i))
+	e.w.Write(e.buf[:n])
+}
+
+func (e *encoder) int(i int64) {
+	n := binary.PutVarint(e.buf[:], i)
+	e.w.Write(e.buf[:n])
+}
+
+func (e *encoder) ints(ints []int) {
+	e.uint(len(ints))
+	for _, i := range ints {
+		e.int(int64(i))
+	}
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.w.WriteByte(1)
+	} else {
+		e.w.WriteByte(0)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.uint(len(s))
+	e.w.WriteString(s)
+}
+
+func (e *encoder) constant(val interpreter.Value) {
+	switch val := val.(type) {
+	case int32:
+		e.w.WriteByte(kindInt)
+		e.int(int64(val))
+	case float64:
+		e.w.WriteByte(kindReal)
+		binary.LittleEndian.PutUint64(e.buf[:], math.Float64bits(val))
+		e.w.Write(e.buf[:8])
+	case bool:
+		e.w.WriteByte(kindBool)
+		e.bool(val)
+	case byte:
+		e.w.WriteByte(kindChar)
+		e.w.WriteByte(val)
+	case string:
+		e.w.WriteByte(kindString)
+		e.string(val)
+	default:
+		panic("constant which is not scalar")
+	}
+}
+
+func (e *encoder) variableType(t *compiler.VariableType) {
+	switch {
+	case t.ARRAY != nil:
+		e.w.WriteByte(kindArray)
+		e.uint(len(t.ARRAY.Dimensions))
+		for _, dimension := range t.ARRAY.Dimensions {
+			e.int(dimension.Lower.Int())
+			e.int(dimension.Upper.Int())
+		}
+		e.variableType(t.ARRAY.Element)
+	case t.Int != nil:
+		e.w.WriteByte(kindInt)
+	case t.REAL != nil:
+		e.w.WriteByte(kindReal)
+	case t.BOOL != nil:
+		e.w.WriteByte(kindBool)
+	case t.CHAR != nil:
+		e.w.WriteByte(kindChar)
+	case t.STRING != nil:
+		e.w.WriteByte(kindString)
+	case t.DATE != nil:
+		e.w.WriteByte(kindDate)
+	case t.CUSTOM != nil:
+		e.w.WriteByte(kindCustom)
+		e.string(*t.CUSTOM)
+	default:
+		panic("unknown type")
+	}
+}
+
+// maxValues limits the number of values created for a type or for all the global variables,
+// so that a broken file would not exhaust the memory when the VM initializes them.
+const maxValues = 1 << 24
+
+// ErrFormat is given when a .pbc file could not be decoded.
+var ErrFormat = errors.New("invalid bytecode file")
+
+// Decode reads a program in the format of .pbc files.
+// The program is verified, so that the VM could run it without checking the indices.
+func Decode(r io.Reader) (p *Program, err error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	// Reading stops at the first error, which is raised as a panic.
+	defer func() {
+		if r := recover(); r != nil {
+			decodeErr, ok := r.(decodeError)
+			if !ok {
+				panic(r)
+			}
+			p, err = nil, decodeErr.err
+		}
+	}()
+
+	header := make([]byte, len(magic))
+	d.read(header)
+	if string(header) != magic {
+		d.fail("not a bytecode file or an unsupported version")
+	}
+	p = &Program{}
+	p.Constants = make([]interpreter.Value, d.count())
+	for index := range p.Constants {
+		p.Constants[index] = d.constant()
+	}
+	p.Records = make([]*compiler.InstTypeDefinition, d.count())
+	for index := range p.Records {
+		record := &compiler.InstTypeDefinition{Name: d.string()}
+		record.Fields = make([]*compiler.InstDeclareVariable, d.count())
+		for index := range record.Fields {
+			record.Fields[index] = &compiler.InstDeclareVariable{Name: d.string(), Type: *d.variableType()}
+		}
+		p.Records[index] = record
+	}
+	p.Types = make([]*compiler.VariableType, d.count())
+	for index := range p.Types {
+		p.Types[index] = d.variableType()
+	}
+	p.Globals = d.ints()
+	p.Natives = make([]*Native, d.count())
+	for index := range p.Natives {
+		p.Natives[index] = &Native{Name: d.string(), Arity: d.count()}
+	}
+	p.Functions = make([]*Function, d.count())
+	for index := range p.Functions {
+		function := &Function{Name: d.string()}
+		function.Params = make([]*Param, d.count())
+		for index := range function.Params {
+			function.Params[index] = &Param{BYREF: d.byte() != 0, Type: int(d.int())}
+		}
+		function.Return = int(d.int())
+		function.Locals = d.ints()
+		function.Code = make([]Instruction, d.count())
+		line := int32(0)
+		for index := range function.Code {
+			inst := &function.Code[index]
+			inst.Op = Opcode(d.byte())
+			inst.Arg = int32(d.int())
+			inst.Line = line + int32(d.int())
+			line = inst.Line
+		}
+		p.Functions[index] = function
+	}
+	if err := p.verify(); err != nil {
+		return nil, err
+	}
+	p.decoded = true
+	return p, nil
+}
+
+// decodeError is raised by the decoder and recovered by Decode.
+type decodeError struct {
+	err error
+}
+
+// decoder reads the parts of a .pbc file.
+type decoder struct {
+	r *bufio.Reader
+}
+
+func (d *decoder) fail(message string) {
+	panic(decodeError{fmt.Errorf("%s: %s", ErrFormat, message)})
+}
+
+func (d *decoder) check(err error) {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		d.fail("unexpected end of file")
+	}
+	if err != nil {
+		panic(decodeError{err})
+	}
+}
+
+func (d *decoder) read(buf []byte) {
+	_, err := io.ReadFull(d.r, buf)
+	d.check(err)
+}
+
+func (d *decoder) byte() byte {
+	b, err := d.r.ReadByte()
+	d.check(err)
+	return b
+}
+
+func (d *decoder) int() int64 {
+	i, err := binary.ReadVarint(d.r)
+	d.check(err)
+	return i
+}
+
+// count reads the length of a list, which is limited so that
+// a broken file would not make huge allocations.
+func (d *decoder) count() int {
+	i, err := binary.ReadUvarint(d.r)
+	d.check(err)
+	if i > 1<<24 {
+		d.fail("too many items")
+	}
+	return int(i)
+}
+
+func (d *decoder) ints() []int {
+	ints := make([]int, d.count())
+	for index := range ints {
+		ints[index] = int(d.int())
+	}
+	return ints
+}
+
+func (d *decoder) string() string {
+	buf := make([]byte, d.count())
+	d.read(buf)
+	return string(buf)
+}
+
+func (d *decoder) constant() interpreter.Value {
+	switch d.byte() {
+	case kindInt:
+		return int32(d.int())
+	case kindReal:
+		buf := make([]byte, 8)
+		d.read(buf)
+		return math.Float64frombits(binary.LittleEndian.Uint64(buf))
+	case kindBool:
+		return d.byte() != 0
+	case kindChar:
+		return d.byte()
+	case kindString:
+		return d.string()
+	}
+	d.fail("unknown kind of constant")
+	return nil
+}
+
+// variableType reads a type into the form given by the parser.
+func (d *decoder) variableType() *compiler.VariableType {
+	name := func(s string) *string { return &s }
+	t := &compiler.VariableType{}
+	switch d.byte() {
+	case kindInt:
+		t.Int = name("INT")
+	case kindReal:
+		t.REAL = name("REAL")
+	case kindBool:
+		t.BOOL = name("BOOL")
+	case kindChar:
+		t.CHAR = name("CHAR")
+	case kindString:
+		t.STRING = name("STRING")
+	case kindDate:
+		t.DATE = name("DATE")
+	case kindArray:
+		t.ARRAY = &compiler.ArrayType{Dimensions: make([]*compiler.ArrayDimension, d.count())}
+		length := int64(1)
+		for index := range t.ARRAY.Dimensions {
+			lower, upper := d.int(), d.int()
+			if upper < lower {
+				d.fail("invalid bounds of array")
+			}
+			// The difference is exact as unsigned, even if it overflows int64.
+			span := uint64(upper - lower)
+			if span >= maxValues {
+				d.fail("array is too large")
+			}
+			length *= int64(span) + 1
+			if length > maxValues {
+				d.fail("array is too large")
+			}
+			t.ARRAY.Dimensions[index] = &compiler.ArrayDimension{Lower: bound(lower), Upper: bound(upper)}
+		}
+		t.ARRAY.Element = d.variableType()
+	case kindCustom:
+		t.CUSTOM = name(d.string())
+	default:
+		d.fail("unknown kind of type")
+	}
+	return t
+}
+
+// bound gives the ArrayBound of the integer.
+func bound(i int64) compiler.ArrayBound {
+	if i < 0 {
+		return compiler.ArrayBound{Negative: true, Value: -i}
+	}
+	return compiler.ArrayBound{Value: i}
+}
+
+// verify checks that the indices in the program are in range,
+// and that record types are defined and do not contain themselves.
+// Types and global variables are limited to maxValues values.
+func (p *Program) verify() error {
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s: %s", ErrFormat, fmt.Sprintf(format, args...))
+	}
+	records := make(map[string]*compiler.InstTypeDefinition)
+	for _, record := range p.Records {
+		records[record.Name] = record
+	}
+	// sizes are the numbers of values in records, which are 0 while being counted,
+	// so that a record containing itself is found.
+	sizes := make(map[string]int64)
+	var checkType func(t *compiler.VariableType) (int64, error)
+	checkType = func(t *compiler.VariableType) (int64, error) {
+		var size int64
+		switch {
+		case t.ARRAY != nil:
+			element, err := checkType(t.ARRAY.Element)
+			if err != nil {
+				return 0, err
+			}
+			size = element
+			for _, dimension := range t.ARRAY.Dimensions {
+				size *= dimension.Length()
+			}
+		case t.CUSTOM != nil:
+			record, ok := records[*t.CUSTOM]
+			if !ok {
+				return 0, fail("undefined type %s", *t.CUSTOM)
+			}
+			if size, ok = sizes[record.Name]; ok {
+				if size == 0 {
+					return 0, fail("type %s contains itself", record.Name)
+				}
+				return size, nil
+			}
+			sizes[record.Name] = 0
+			for _, field := range record.Fields {
+				fieldSize, err := checkType(&field.Type)
+				if err != nil {
+					return 0, err
+				}
+				if size += fieldSize; size > maxValues {
+					break
+				}
+			}
+			sizes[record.Name] = size
+		default:
+			size = 1
+		}
+		if size > maxValues {
+			return 0, fail("type is too large")
+		}
+		return size, nil
+	}
+	for _, record := range p.Records {
+		if _, err := checkType(&compiler.VariableType{CUSTOM: &record.Name}); err != nil {
+			return err
+		}
+	}
+	typeSizes := make([]int64, len(p.Types))
+	for index, t := range p.Types {
+		size, err := checkType(t)
+		if err != nil {
+			return err
+		}
+		typeSizes[index] = size
+	}
+	inRange := func(index int, length int) bool {
+		return index >= 0 && index < length
+	}
+	globalsSize := int64(0)
+	for _, t := range p.Globals {
+		if !inRange(t, len(p.Types)) {
+			return fail("type %d out of range", t)
+		}
+		if globalsSize += typeSizes[t]; globalsSize > maxValues {
+			return fail("global variables are too large")
+		}
+	}
+	if len(p.Functions) == 0 {
+		return fail("no main block")
+	}
+	for _, function := range p.Functions {
+		if function.Return != -1 && !inRange(function.Return, len(p.Types)) {
+			return fail("type %d out of range", function.Return)
+		}
+		if len(function.Params) > len(function.Locals) {
+			return fail("parameters of %s are not locals", function.Name)
+		}
+		for _, param := range function.Params {
+			if !inRange(param.Type, len(p.Types)) {
+				return fail("type %d out of range", param.Type)
+			}
+		}
+		for _, t := range function.Locals {
+			if t != -1 && !inRange(t, len(p.Types)) {
+				return fail("type %d out of range", t)
+			}
+		}
+		if len(function.Code) == 0 || function.Code[len(function.Code)-1].Op != OpReturn {
+			return fail("%s does not end with return", function.Name)
+		}
+		for pc, inst := range function.Code {
+			arg := int(inst.Arg)
+			var ok bool
+			switch inst.Op {
+			case OpConst:
+				ok = inRange(arg, len(p.Constants))
+			case OpField:
+				ok = inRange(arg, len(p.Constants))
+				if ok {
+					_, ok = p.Constants[arg].(string)
+				}
+			case OpGlobal:
+				ok = inRange(arg, len(p.Globals))
+			case OpLocal, OpSetLocal:
+				ok = inRange(arg, len(function.Locals))
+			case OpInitLocal:
+				ok = inRange(arg, len(function.Locals)) && function.Locals[arg] != -1
+			case OpForPrep, OpForTest:
+				ok = inRange(arg+1, len(function.Locals))
+			case OpJump, OpJumpIfFalse, OpJumpIfTrue:
+				ok = inRange(arg, len(function.Code))
+			case OpCall:
+				ok = inRange(arg, len(p.Functions)) && arg != 0
+			case OpCallNative:
+				ok = inRange(arg, len(p.Natives))
+			case OpBound, OpIndex:
+				ok = arg >= 0
+			case OpReturn:
+				ok = arg == 0 || arg == 1
+			default:
+				ok = inst.Op < opcodeCount
+			}
+			if !ok {
+				return fail("invalid instruction %s at %d of %s", inst, pc, function.Name)
+			}
+		}
+	}
+	return nil
+}
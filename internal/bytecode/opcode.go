@@ -0,0 +1,170 @@
+package bytecode
+
+import "fmt"
+
+// Opcode is the operation of an instruction.
+// The VM is a stack machine: operands are popped from the stack,
+// and results are pushed back.
+// References to variables are pushed as pointers, so that they could be assigned.
+type Opcode byte
+
+const (
+	// OpConst pushes the constant at Arg of the constant pool.
+	OpConst Opcode = iota
+	// OpPop drops the value on the top.
+	OpPop
+	// OpDup pushes the value on the top again.
+	OpDup
+
+	// OpGlobal pushes the reference to the global variable at Arg.
+	OpGlobal
+	// OpLocal pushes the reference to the local variable at Arg.
+	OpLocal
+	// OpInitLocal creates the local variable at Arg with its initial value.
+	OpInitLocal
+	// OpSetLocal pops a value into a new local variable at Arg.
+	// It is used for values kept by FOR and CASE.
+	OpSetLocal
+	// OpField pops a reference to a record,
+	// and pushes the reference to the field named by the constant at Arg.
+	OpField
+	// OpBound checks the index on the top against the dimension Arg of the array,
+	// whose reference is under the indices.
+	OpBound
+	// OpIndex pops Arg indices and the reference to an array,
+	// and pushes the reference to the element.
+	OpIndex
+	// OpLoad pops a reference and pushes the value.
+	OpLoad
+	// OpStore pops a value and a reference, and assigns the value.
+	OpStore
+
+	// OpAdd and the following operators pop two values and push the result.
+	OpAdd
+	OpMinus
+	OpMultiple
+	OpDivide
+	OpIntDivide
+	OpMod
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+	// OpOpposite and OpNot pop one value and push the result.
+	OpOpposite
+	OpNot
+
+	// OpJump jumps to Arg.
+	OpJump
+	// OpJumpIfFalse pops a BOOL and jumps to Arg if it is FALSE.
+	OpJumpIfFalse
+	// OpJumpIfTrue pops a BOOL and jumps to Arg if it is TRUE.
+	OpJumpIfTrue
+
+	// OpForPrep pops the step, the end, the start and the reference to the counter.
+	// The counter is assigned the start,
+	// and the end and the step are kept in the local variables at Arg and Arg+1.
+	OpForPrep
+	// OpForTest pops the counter and pushes if the loop should go on.
+	OpForTest
+
+	// OpOutput pops a value and outputs it.
+	OpOutput
+	// OpNewline ends the line of OUTPUT.
+	OpNewline
+	// OpInput pops a reference and reads a line into it.
+	OpInput
+
+	// OpCall calls the function at Arg with the arguments on the stack.
+	// Arguments passed BYREF are references.
+	OpCall
+	// OpCallNative calls the built-in or runtime function at Arg of the natives.
+	OpCallNative
+	// OpReturn returns from the function.
+	// The value on the top is returned if Arg is 1, otherwise nothing is returned.
+	// Either way, the caller gets a value on the stack, which is nil for procedures.
+	OpReturn
+
+	opcodeCount
+)
+
+var opcodeNames = [...]string{
+	OpConst:        "const",
+	OpPop:          "pop",
+	OpDup:          "dup",
+	OpGlobal:       "global",
+	OpLocal:        "local",
+	OpInitLocal:    "initlocal",
+	OpSetLocal:     "setlocal",
+	OpField:        "field",
+	OpBound:        "bound",
+	OpIndex:        "index",
+	OpLoad:         "load",
+	OpStore:        "store",
+	OpAdd:          "add",
+	OpMinus:        "minus",
+	OpMultiple:     "multiple",
+	OpDivide:       "divide",
+	OpIntDivide:    "intdivide",
+	OpMod:          "mod",
+	OpEqual:        "eq",
+	OpNotEqual:     "ne",
+	OpLess:         "lt",
+	OpLessEqual:    "le",
+	OpGreater:      "gt",
+	OpGreaterEqual: "ge",
+	OpOpposite:     "opposite",
+	OpNot:          "not",
+	OpJump:         "jump",
+	OpJumpIfFalse:  "jumpiffalse",
+	OpJumpIfTrue:   "jumpiftrue",
+	OpForPrep:      "forprep",
+	OpForTest:      "fortest",
+	OpOutput:       "output",
+	OpNewline:      "newline",
+	OpInput:        "input",
+	OpCall:         "call",
+	OpCallNative:   "callnative",
+	OpReturn:       "return",
+}
+
+func (op Opcode) String() string {
+	if op < opcodeCount {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("op(%d)", byte(op))
+}
+
+// comparisonOperators are the operators of pseudocode for the comparison opcodes.
+var comparisonOperators = map[Opcode]string{
+	OpEqual:        "=",
+	OpNotEqual:     "<>",
+	OpLess:         "<",
+	OpLessEqual:    "<=",
+	OpGreater:      ">",
+	OpGreaterEqual: ">=",
+}
+
+// comparisonOpcodes are the opcodes of the comparison operators.
+var comparisonOpcodes = map[string]Opcode{
+	"=":  OpEqual,
+	"<>": OpNotEqual,
+	"<":  OpLess,
+	"<=": OpLessEqual,
+	">":  OpGreater,
+	">=": OpGreaterEqual,
+}
+
+// Instruction is an opcode with its argument.
+// Line is the line in the source, which is given in runtime errors.
+type Instruction struct {
+	Op   Opcode
+	Arg  int32
+	Line int32
+}
+
+func (inst Instruction) String() string {
+	return fmt.Sprintf("%-12s %d", inst.Op, inst.Arg)
+}
@@ -0,0 +1,70 @@
+package bytecode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HankelBao/Pseudo/internal/compiler"
+	"github.com/HankelBao/Pseudo/internal/interpreter"
+)
+
+// Program is a compiled program for the VM.
+// It keeps everything needed to run, so that it could be saved without the source.
+type Program struct {
+	// Constants are the values used by OpConst,
+	// and names of fields used by OpField.
+	Constants []interpreter.Value
+	// Records are the record types defined by TYPE.
+	Records []*compiler.InstTypeDefinition
+	// Types are the types of variables, referred by their indices.
+	Types []*compiler.VariableType
+	// Globals are the types of global variables.
+	Globals []int
+	// Natives are the built-in and runtime functions called.
+	Natives []*Native
+	// Functions are the subroutines, and the first one is the main block.
+	Functions []*Function
+
+	// decoded tells the program is read from a file instead of compiled from checked source,
+	// so that it might be broken in ways which are not verified.
+	decoded bool
+}
+
+// Native is a built-in or runtime function called by OpCallNative.
+type Native struct {
+	Name  string
+	Arity int
+}
+
+// Function is a subroutine, or the main block.
+type Function struct {
+	Name   string
+	Params []*Param
+	// Return is the type of the returned value, or -1 for procedures and the main block.
+	Return int
+	// Locals are the types of local variables, and the parameters come first.
+	// Variables kept by FOR and CASE have no types, which are -1.
+	Locals []int
+	Code   []Instruction
+}
+
+// Param is a parameter of a subroutine.
+type Param struct {
+	BYREF bool
+	Type  int
+}
+
+// String gives the listing of the program, which is useful for debugging.
+func (p *Program) String() string {
+	var b strings.Builder
+	for index, constant := range p.Constants {
+		fmt.Fprintf(&b, "const %d: %#v\n", index, constant)
+	}
+	for index, function := range p.Functions {
+		fmt.Fprintf(&b, "function %d %s:\n", index, function.Name)
+		for pc, inst := range function.Code {
+			fmt.Fprintf(&b, "\t%4d  %s\n", pc, inst)
+		}
+	}
+	return b.String()
+}
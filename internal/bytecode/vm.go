@@ -0,0 +1,287 @@
+package bytecode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/HankelBao/Pseudo/internal/interpreter"
+)
+
+// ErrLimit is given when the program runs more instructions than the limit.
+var ErrLimit = errors.New("instruction limit exceeded")
+
+// VM runs bytecode programs.
+// Values, operations and the runtime are shared with the interpreter,
+// so that it behaves the same as the compiled program.
+type VM struct {
+	*interpreter.Runtime
+	Program *Program
+	// Limit is the maximum number of instructions to run, and 0 means no limit.
+	Limit int64
+	// Steps is the number of instructions that have been run.
+	Steps int64
+
+	types   *interpreter.Scope
+	globals []interpreter.Value
+	stack   []interpreter.Value
+	frames  []*frame
+}
+
+// frame is a running function.
+// Locals are pointers, so that parameters passed BYREF share the variables of the caller.
+type frame struct {
+	function *Function
+	pc       int
+	locals   []*interpreter.Value
+}
+
+// NewVM creates a VM for the program with the input and outputs of the program.
+func NewVM(program *Program, stdin io.Reader, stdout io.Writer, stderr io.Writer) *VM {
+	vm := &VM{
+		Runtime: interpreter.NewRuntime(stdin, stdout, stderr),
+		Program: program,
+		types:   interpreter.NewGlobalScope(),
+	}
+	for _, record := range program.Records {
+		vm.types.RegisterType(record)
+	}
+	return vm
+}
+
+// Run runs the program and gives its exit code.
+// ErrLimit is given if the limit is exceeded, and the output before it is kept.
+func (vm *VM) Run() (code int, err error) {
+	defer vm.Finish(&code, &err)
+	// Programs from broken files could still go wrong, such as popping an empty stack.
+	// Programs compiled from checked source should not, so it is a bug at the line then.
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(interpreter.Exit); ok {
+				panic(r)
+			}
+			if vm.Program.decoded {
+				err = fmt.Errorf("%s: %v at line %d", ErrFormat, r, vm.line())
+			} else {
+				err = fmt.Errorf("internal error at line %d: %v", vm.line(), r)
+			}
+		}
+	}()
+
+	vm.globals = make([]interpreter.Value, len(vm.Program.Globals))
+	for index, t := range vm.Program.Globals {
+		vm.globals[index] = vm.initial(t)
+	}
+	vm.stack = vm.stack[:0]
+	main := vm.Program.Functions[0]
+	vm.frames = []*frame{{function: main, locals: make([]*interpreter.Value, len(main.Locals))}}
+	return 0, vm.loop()
+}
+
+// line gives the line in the source of the instruction being run.
+func (vm *VM) line() int32 {
+	if len(vm.frames) == 0 {
+		return 0
+	}
+	f := vm.frames[len(vm.frames)-1]
+	if f.pc == 0 || f.pc > len(f.function.Code) {
+		return 0
+	}
+	return f.function.Code[f.pc-1].Line
+}
+
+// initial gives the initial value of the type.
+func (vm *VM) initial(t int) interpreter.Value {
+	return interpreter.Initial(vm.Program.Types[t], vm.types)
+}
+
+// convert converts the value to the type when it is passed or returned.
+// It follows interpreter.Convert, which converts INT to REAL only.
+func (vm *VM) convert(val interpreter.Value, t int) interpreter.Value {
+	if i, ok := val.(int32); ok && vm.Program.Types[t].REAL != nil {
+		return float64(i)
+	}
+	return interpreter.Copy(val)
+}
+
+func (vm *VM) push(val interpreter.Value) {
+	vm.stack = append(vm.stack, val)
+}
+
+func (vm *VM) pop() interpreter.Value {
+	val := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return val
+}
+
+func (vm *VM) popRef() *interpreter.Value {
+	return vm.pop().(*interpreter.Value)
+}
+
+// loop runs instructions until the main block returns.
+func (vm *VM) loop() error {
+	f := vm.frames[len(vm.frames)-1]
+	code := f.function.Code
+	for {
+		if vm.Limit > 0 && vm.Steps >= vm.Limit {
+			return ErrLimit
+		}
+		vm.Steps++
+		inst := code[f.pc]
+		f.pc++
+
+		switch inst.Op {
+		case OpConst:
+			vm.push(vm.Program.Constants[inst.Arg])
+		case OpPop:
+			vm.pop()
+		case OpDup:
+			vm.push(vm.stack[len(vm.stack)-1])
+
+		case OpGlobal:
+			vm.push(&vm.globals[inst.Arg])
+		case OpLocal:
+			vm.push(f.locals[inst.Arg])
+		case OpInitLocal:
+			val := vm.initial(f.function.Locals[inst.Arg])
+			f.locals[inst.Arg] = &val
+		case OpSetLocal:
+			val := vm.pop()
+			f.locals[inst.Arg] = &val
+		case OpField:
+			record := (*vm.popRef()).(*interpreter.Record)
+			name := vm.Program.Constants[inst.Arg].(string)
+			vm.push(&record.Fields[record.Field(name)])
+		case OpBound:
+			top := len(vm.stack) - 1
+			index := int64(vm.stack[top].(int32))
+			array := (*vm.stack[top-1-int(inst.Arg)].(*interpreter.Value)).(*interpreter.Array)
+			dimension := array.Dimensions[inst.Arg]
+			lower, upper := dimension.Lower.Int(), dimension.Upper.Int()
+			if index < lower || index > upper {
+				vm.Fatalf("Index %d out of bounds [%d:%d] at line %d", index, lower, upper, inst.Line)
+			}
+		case OpIndex:
+			base := len(vm.stack) - int(inst.Arg)
+			array := (*vm.stack[base-1].(*interpreter.Value)).(*interpreter.Array)
+			offset := int64(0)
+			for i, index := range vm.stack[base:] {
+				dimension := array.Dimensions[i]
+				offset = offset*dimension.Length() + int64(index.(int32)) - dimension.Lower.Int()
+			}
+			vm.stack = vm.stack[:base-1]
+			vm.push(&array.Elements[offset])
+		case OpLoad:
+			vm.push(*vm.popRef())
+		case OpStore:
+			val := vm.pop()
+			ref := vm.popRef()
+			*ref = interpreter.Convert(val, *ref)
+
+		case OpAdd:
+			value2, value1 := vm.pop(), vm.pop()
+			vm.push(interpreter.Add(value1, value2))
+		case OpMinus:
+			value2, value1 := vm.pop(), vm.pop()
+			vm.push(interpreter.Minus(value1, value2))
+		case OpMultiple:
+			value2, value1 := vm.pop(), vm.pop()
+			vm.push(interpreter.Multiple(value1, value2))
+		case OpDivide:
+			value2, value1 := vm.pop(), vm.pop()
+			vm.push(interpreter.Divide(value1, value2))
+		case OpIntDivide:
+			value2, value1 := vm.pop(), vm.pop()
+			vm.push(vm.IntDivide(int(inst.Line), value1, value2))
+		case OpMod:
+			value2, value1 := vm.pop(), vm.pop()
+			vm.push(vm.Mod(int(inst.Line), value1, value2))
+		case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+			value2, value1 := vm.pop(), vm.pop()
+			vm.push(interpreter.Compare(comparisonOperators[inst.Op], value1, value2))
+		case OpOpposite:
+			vm.push(interpreter.Opposite(vm.pop()))
+		case OpNot:
+			vm.push(!vm.pop().(bool))
+
+		case OpJump:
+			f.pc = int(inst.Arg)
+		case OpJumpIfFalse:
+			if !vm.pop().(bool) {
+				f.pc = int(inst.Arg)
+			}
+		case OpJumpIfTrue:
+			if vm.pop().(bool) {
+				f.pc = int(inst.Arg)
+			}
+
+		case OpForPrep:
+			step, end, start := vm.pop(), vm.pop(), vm.pop()
+			counter := vm.popRef()
+			step = interpreter.Convert(step, *counter)
+			end = interpreter.Convert(end, *counter)
+			*counter = interpreter.Convert(start, *counter)
+			f.locals[inst.Arg] = &end
+			f.locals[inst.Arg+1] = &step
+		case OpForTest:
+			counter := vm.pop()
+			end, step := *f.locals[inst.Arg], *f.locals[inst.Arg+1]
+			if interpreter.Compare(">=", step, int32(0)) {
+				vm.push(interpreter.Compare("<=", counter, end))
+			} else {
+				vm.push(interpreter.Compare(">=", counter, end))
+			}
+
+		case OpOutput:
+			vm.Output(vm.pop())
+		case OpNewline:
+			vm.OutputNewline()
+		case OpInput:
+			vm.Input(vm.popRef(), int(inst.Line))
+
+		case OpCall:
+			// Frames below main are the calls, which follow the depth of the interpreter.
+			if len(vm.frames)-1 >= interpreter.MaxCallDepth {
+				vm.Fatalf("Stack overflow at line %d", inst.Line)
+			}
+			callee := vm.Program.Functions[inst.Arg]
+			locals := make([]*interpreter.Value, len(callee.Locals))
+			base := len(vm.stack) - len(callee.Params)
+			for index, param := range callee.Params {
+				arg := vm.stack[base+index]
+				if param.BYREF {
+					locals[index] = arg.(*interpreter.Value)
+					continue
+				}
+				val := vm.convert(arg, param.Type)
+				locals[index] = &val
+			}
+			vm.stack = vm.stack[:base]
+			f = &frame{function: callee, locals: locals}
+			vm.frames = append(vm.frames, f)
+			code = callee.Code
+		case OpCallNative:
+			native := vm.Program.Natives[inst.Arg]
+			base := len(vm.stack) - native.Arity
+			params := make([]interpreter.Value, native.Arity)
+			copy(params, vm.stack[base:])
+			vm.stack = vm.stack[:base]
+			vm.push(vm.Call(native.Name, params, int(inst.Line)))
+		case OpReturn:
+			var returned interpreter.Value
+			if inst.Arg == 1 {
+				returned = vm.convert(vm.pop(), f.function.Return)
+			}
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == 0 {
+				return nil
+			}
+			f = vm.frames[len(vm.frames)-1]
+			code = f.function.Code
+			vm.push(returned)
+
+		default:
+			panic("unknown opcode " + inst.Op.String())
+		}
+	}
+}
@@ -15,24 +15,35 @@ type Instruction struct {
 	Output          *InstOutput          ` @@`
 	Input           *InstInput           `|@@`
 	Call            *InstCall            `|@@`
-	PrintfD         *InstPrintfD         `|@@`
-	PrintfF         *InstPrintfF         `|@@`
 	DeclareVariable *InstDeclareVariable `|@@`
+	TypeDefinition  *InstTypeDefinition  `|@@`
+	Procedure       *InstProcedure       `|@@`
+	Function        *InstFunction        `|@@`
+	Return          *InstReturn          `|@@`
 	ConditionBr     *InstConditionBr     `|@@`
 	While           *InstWhile           `|@@`
 	Repeat          *InstRepeat          `|@@`
+	For             *InstFor             `|@@`
+	Case            *InstCase            `|@@`
 	Assignment      *InstAssignment      `|@@`
 	NullLine        *string              `|@EOL`
 }
 
-// InstOutput outputs string
+// InstOutput outputs values in a line
+// Each item is formatted according to its type.
+//
 // Example:
-// 	OUTPUT "Hello World!\n"
+// 	OUTPUT "Total: ", total, " avg ", avg
 type InstOutput struct {
-	Pos     lexer.Position
-	Content Expression `"OUTPUT" @@ EOL`
+	Pos   lexer.Position
+	Items []*Expression `"OUTPUT" @@ ("," @@)* EOL`
 }
 
+// InstInput reads a line from input into a variable.
+// The line is converted according to the type of the variable.
+//
+// Example:
+// 	INPUT Age
 type InstInput struct {
 	Pos     lexer.Position
 	Content Key `"INPUT" @@ EOL`
@@ -48,6 +59,21 @@ type InstDeclareVariable struct {
 	Type VariableType `":" @@ EOL`
 }
 
+// InstTypeDefinition defines a record type.
+// Fields are declared just like variables.
+//
+// Example:
+// 	TYPE Student
+// 		DECLARE Name : STRING
+// 		DECLARE Age : INT
+// 	ENDTYPE
+type InstTypeDefinition struct {
+	Pos    lexer.Position
+	Name   string                 `"TYPE" @Ident EOL`
+	Fields []*InstDeclareVariable `(@@ | EOL)*`
+	END    string                 `"ENDTYPE" EOL`
+}
+
 // InstAssignment assigns a variable the value of an expression
 //
 // Example:
@@ -58,24 +84,6 @@ type InstAssignment struct {
 	Right Expression `"-" @@ EOL`
 }
 
-// InstPrintfD outputs a expression of INT for debug usage
-//
-// Example:
-// 	PrintfS 1
-type InstPrintfD struct {
-	Pos     lexer.Position
-	Content Expression `"PrintfD" @@ EOL`
-}
-
-// InstPrintfF outputs a expression of REAL for debug usage
-//
-// Example:
-// 	PrintF 1.0
-type InstPrintfF struct {
-	Pos     lexer.Position
-	Content Expression `"PrintfF" @@ EOL`
-}
-
 // InstConditionBr creates if..then..else...
 //
 // Example:
@@ -117,22 +125,168 @@ type InstRepeat struct {
 	Condition Expression `"UNTIL" @@ EOL`
 }
 
+// InstFor creates counted loop
+// STEP is 1 if not given, and it could be negative.
+// The identifier after NEXT could be omitted.
+//
+// Example:
+//	FOR i <- 1 TO 10 STEP 2
+// 		OUTPUT "Hi"
+//	NEXT i
+type InstFor struct {
+	Pos     lexer.Position
+	Counter string      `"FOR" @Ident "<"`
+	Start   Expression  `"-" @@`
+	End     Expression  `"TO" @@`
+	Step    *Expression `("STEP" @@)? EOL`
+	Body    Ast         `@@`
+	Next    *string     `"NEXT" (@Ident)? EOL`
+}
+
+// InstCase creates case block
+// A clause starts with labels, which are constants or ranges of constants.
+// The first clause matching the value is run,
+// or OTHERWISE if none of them matches.
+//
+// Example:
+//	CASE OF Grade
+//		'A' : OUTPUT "Excellent"
+//		'B', 'C' : OUTPUT "Good"
+//		'D' TO 'F' : OUTPUT "Poor"
+//		OTHERWISE : OUTPUT "Unknown"
+//	ENDCASE
+type InstCase struct {
+	Pos       lexer.Position
+	Value     Expression    `"CASE" "OF" @@ EOL`
+	Clauses   []*CaseClause `(@@ | EOL)*`
+	Otherwise *Ast          `("OTHERWISE" ":" @@)?`
+	END       string        `"ENDCASE" EOL`
+}
+
+// CaseClause is a clause of CASE.
+type CaseClause struct {
+	Pos    lexer.Position
+	Labels []*CaseLabel `@@ ("," @@)* ":"`
+	Body   Ast          `@@`
+}
+
+// CaseLabel matches a value or a range of values.
+type CaseLabel struct {
+	Pos  lexer.Position
+	From CaseValue  `@@`
+	To   *CaseValue `("TO" @@)?`
+}
+
+// CaseValue is a constant in labels of CASE, which could be negative.
+type CaseValue struct {
+	Pos      lexer.Position
+	Negative bool     `@"-"?`
+	Constant Constant `@@`
+}
+
 // InstCall creates call block
+// Brackets could be omitted if there are no arguments.
 //
 // Example:
 // 	CALL puts("Hello World!")
+// 	CALL ShowMenu
 type InstCall struct {
 	Pos      lexer.Position
-	Function *FunctionCall `"CALL" @@ EOL`
+	Function *FunctionCall `"CALL" ( @@`
+	Name     *string       `| @Ident ) EOL`
+}
+
+// InstProcedure defines a procedure.
+// Procedures could be called before they are defined.
+//
+// Example:
+// 	PROCEDURE Swap(BYREF a : INT, BYREF b : INT)
+// 		DECLARE t : INT
+// 		t <- a
+// 		a <- b
+// 		b <- t
+// 	ENDPROCEDURE
+type InstProcedure struct {
+	Pos    lexer.Position
+	Name   string       `"PROCEDURE" @Ident`
+	Params []*Parameter `("(" (@@ ("," @@)*)? ")")? EOL`
+	Body   Ast          `@@`
+	END    string       `"ENDPROCEDURE" EOL`
+}
+
+// InstFunction defines a function which returns a value.
+// Every path of the body should end with RETURN.
+//
+// Example:
+// 	FUNCTION Max(a : INT, b : INT) RETURNS INT
+// 		IF a > b
+// 		  THEN
+// 		    RETURN a
+// 		ENDIF
+// 		RETURN b
+// 	ENDFUNCTION
+type InstFunction struct {
+	Pos        lexer.Position
+	Name       string       `"FUNCTION" @Ident`
+	Params     []*Parameter `("(" (@@ ("," @@)*)? ")")?`
+	ReturnType VariableType `"RETURNS" @@ EOL`
+	Body       Ast          `@@`
+	END        string       `"ENDFUNCTION" EOL`
+}
+
+// InstReturn returns a value from the function.
+//
+// Example:
+// 	RETURN a + b
+type InstReturn struct {
+	Pos   lexer.Position
+	Value Expression `"RETURN" @@ EOL`
+}
+
+// Parameter matches a parameter of a procedure or a function.
+// Parameters are passed BYVAL if not specified.
+type Parameter struct {
+	Pos   lexer.Position
+	BYREF bool         `("BYVAL" | @"BYREF")?`
+	Name  string       `@Ident`
+	Type  VariableType `":" @@`
 }
 
 // VariableType matches the variable type of declaration
 type VariableType struct {
 	Pos    lexer.Position
-	BOOL   *string `  @"BOOL"`
-	Int    *string `| @"INT"`
-	REAL   *string `| @"REAL"`
-	CUSTOM *string `| @Ident`
+	ARRAY  *ArrayType `  @@`
+	BOOL   *string    `| @"BOOL"`
+	Int    *string    `| @"INT"`
+	REAL   *string    `| @"REAL"`
+	STRING *string    `| @"STRING"`
+	CHAR   *string    `| @"CHAR"`
+	DATE   *string    `| @"DATE"`
+	CUSTOM *string    `| @Ident`
+}
+
+// ArrayType matches the type of an array.
+// Each dimension has its own lower and upper bound.
+//
+// Example:
+// 	ARRAY[1:10, 1:10] OF REAL
+type ArrayType struct {
+	Pos        lexer.Position
+	Dimensions []*ArrayDimension `"ARRAY" "[" @@ ("," @@)* "]"`
+	Element    *VariableType     `"OF" @@`
+}
+
+// ArrayDimension matches the bounds of a dimension.
+type ArrayDimension struct {
+	Pos   lexer.Position
+	Lower ArrayBound `@@ ":"`
+	Upper ArrayBound `@@`
+}
+
+// ArrayBound is a bound of a dimension, which could be negative.
+type ArrayBound struct {
+	Negative bool  `@"-"?`
+	Value    int64 `@Int`
 }
 
 // Key is an assignable terminal
@@ -141,10 +295,12 @@ type Key struct {
 	Variables []*Variable `@@ ("." @@)*`
 }
 
+// Variable is a part of Key.
+// Indices are given if it is an element of an array.
 type Variable struct {
-	Pos        lexer.Position
-	Name       string `@Ident`
-	ArrayIndex *int   `("[" @Int "]")?`
+	Pos     lexer.Position
+	Name    string        `@Ident`
+	Indices []*Expression `("[" @@ ("," @@)* "]")?`
 }
 
 // KeyToken is the lexers of Key
@@ -161,61 +317,86 @@ type KeyToken struct {
 
 // Expression is expression of an value
 type Expression struct {
-	//Pos        lexer.Position
-	Comparison Comparison `@@`
+	Pos         lexer.Position
+	Disjunction Disjunction `@@`
+}
+
+// Disjunction gives OR of two or more BOOLs
+// The right side is evaluated only if the left side is FALSE.
+type Disjunction struct {
+	Pos   lexer.Position
+	Head  Conjunction    `@@`
+	Items []*Conjunction `("OR" @@)*`
+}
+
+// Conjunction gives AND of two or more BOOLs
+// The right side is evaluated only if the left side is TRUE.
+type Conjunction struct {
+	Pos   lexer.Position
+	Head  Negation    `@@`
+	Items []*Negation `("AND" @@)*`
+}
+
+// Negation gives NOT of a BOOL
+type Negation struct {
+	Pos        lexer.Position
+	Not        *Negation   `  "NOT" @@`
+	Comparison *Comparison `| @@`
 }
 
 // Comparison compares two or more values
 type Comparison struct {
-	//Pos   lexer.Position
+	Pos   lexer.Position
 	Head  Addition        `@@`
 	Items []*OpComparison `(@@)*`
 }
 
 // OpComparison makes a comparison with another value
 type OpComparison struct {
-	//Pos lexer.Position
+	Pos      lexer.Position
 	Operator string   `@("<" ">" | "=" | "<" "=" | ">" "=" | "<" | ">")`
 	Item     Addition `@@`
 }
 
 // Addition adds two or more values
 type Addition struct {
-	//Pos   lexer.Position
+	Pos   lexer.Position
 	Head  Multiplication `@@`
 	Items []*OpAddition  `(@@)*`
 }
 
 // OpAddition adds another value
 type OpAddition struct {
+	Pos      lexer.Position
 	Operator string         `@("+"|"-")`
 	Item     Multiplication `@@`
 }
 
 // Multiplication multiples two values
 type Multiplication struct {
-	//Pos   lexer.Position
+	Pos   lexer.Position
 	Head  Unary               `@@`
 	Items []*OpMultiplication `(@@)*`
 }
 
 // OpMultiplication multiples with another value
+// / always gives a REAL, while DIV and MOD work on INTs.
 type OpMultiplication struct {
-	Operator string `@("*"|"/")`
+	Pos      lexer.Position
+	Operator string `@("*"|"/"|"DIV"|"MOD")`
 	Item     Unary  `@@`
 }
 
-// Unary gives not or opposite
+// Unary gives opposite
 type Unary struct {
-	//Pos     lexer.Position
-	Not      *Unary   `  "!" @@`
-	Opposite *Unary   `| "-" @@`
+	Pos      lexer.Position
+	Opposite *Unary   `  "-" @@`
 	Primary  *Primary `| @@`
 }
 
 // Primary is the smallest universal unit in an expression
 type Primary struct {
-	//Pos lexer.Position
+	Pos lexer.Position
 
 	Constant      *Constant     `  @@`
 	Function      *FunctionCall `| @@`
@@ -225,14 +406,16 @@ type Primary struct {
 
 // Constant shows direct value
 type Constant struct {
-	//Pos     lexer.Position
+	Pos     lexer.Position
 	VBool   *string  `  @("TRUE"|"FALSE")`
 	VString *string  `| @String`
+	VChar   *string  `| @Char`
 	VReal   *float64 `| @Float`
 	VInt    *int64   `| @Int`
 }
 
 type FunctionCall struct {
+	Pos    lexer.Position
 	Name   string        `@Ident`
 	Params []*Expression `"(" (@@ ("," @@)*)? ")"`
 }
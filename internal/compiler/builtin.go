@@ -0,0 +1,85 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// BuiltinFunction generates IR for a built-in function of the syllabus.
+// Parameters are evaluated before the call.
+type BuiltinFunction func(scope *Scope, f *FunctionCall, params []value.Value) value.Value
+
+// builtinFunctions are functions of the syllabus.
+// They are either compiled inline or backed by the runtime.
+var builtinFunctions = map[string]BuiltinFunction{
+	"ASC":   ascBuiltin,
+	"CHR":   chrBuiltin,
+	"INT":   intBuiltin,
+	"RAND":  randBuiltin,
+	"ROUND": roundBuiltin,
+}
+
+// ascBuiltin gives the character code of a CHAR.
+//
+// Example:
+// 	ASC('A')
+func ascBuiltin(scope *Scope, f *FunctionCall, params []value.Value) value.Value {
+	if len(params) != 1 || params[0].Type() != types.I8 {
+		fatalf(f.Pos, "ASC takes one CHAR")
+	}
+	return scope.Block.NewZExt(params[0], types.I32)
+}
+
+// chrBuiltin gives the CHAR of a character code.
+//
+// Example:
+// 	CHR(65)
+func chrBuiltin(scope *Scope, f *FunctionCall, params []value.Value) value.Value {
+	if len(params) != 1 || params[0].Type() != types.I32 {
+		fatalf(f.Pos, "CHR takes one INT")
+	}
+	return scope.Block.NewTrunc(params[0], types.I8)
+}
+
+// intBuiltin gives the integer part of a REAL.
+//
+// Example:
+// 	INT(27.5415)
+func intBuiltin(scope *Scope, f *FunctionCall, params []value.Value) value.Value {
+	if len(params) != 1 || (params[0].Type() != types.Double && params[0].Type() != types.I32) {
+		fatalf(f.Pos, "INT takes one REAL")
+	}
+	if params[0].Type() == types.I32 {
+		return params[0]
+	}
+	return scope.Block.NewFPToSI(params[0], types.I32)
+}
+
+// randBuiltin gives a random REAL between 0 and the INT, excluding the INT.
+//
+// Example:
+// 	RAND(87)
+func randBuiltin(scope *Scope, f *FunctionCall, params []value.Value) value.Value {
+	if len(params) != 1 || params[0].Type() != types.I32 {
+		fatalf(f.Pos, "RAND takes one INT")
+	}
+	return scope.Block.NewCall(scope.FindFunction("pseudo_rand"), params[0])
+}
+
+// roundBuiltin rounds a REAL to the number of decimal places.
+//
+// Example:
+// 	ROUND(4.87, 1)
+func roundBuiltin(scope *Scope, f *FunctionCall, params []value.Value) value.Value {
+	if len(params) != 2 || params[1].Type() != types.I32 {
+		fatalf(f.Pos, "ROUND takes one REAL and one INT")
+	}
+	number := params[0]
+	if number.Type() == types.I32 {
+		number = IntToRealEval(scope, number)
+	}
+	if number.Type() != types.Double {
+		fatalf(f.Pos, "ROUND takes one REAL and one INT")
+	}
+	return scope.Block.NewCall(scope.FindFunction("pseudo_round"), number, params[1])
+}
@@ -0,0 +1,761 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/llir/llvm/ir/types"
+)
+
+// Checker checks the ast before it is compiled.
+// Identifiers are resolved and types are inferred the same way as the compiler,
+// but it goes on after errors, so that all of them could be reported at once.
+type Checker struct {
+	Errors Diagnostics
+
+	types       map[string]*checkRecord
+	subroutines map[string]*checkSubroutine
+	bodies      []*checkBody
+	// globals are the names of variables declared in the main block,
+	// which subroutines could not be named after.
+	globals map[string]bool
+	// runtime gives the functions of the runtime which could be called.
+	runtime *Scope
+}
+
+// checkSubroutine is a procedure or a function defined in pseudocode.
+type checkSubroutine struct {
+	Name       string
+	Params     []*Parameter
+	ParamTypes []*checkType
+	// ReturnType is nil for procedures.
+	ReturnType *checkType
+}
+
+// checkBody is the body of a subroutine to be checked after the main block.
+type checkBody struct {
+	Pos        lexer.Position
+	Subroutine *checkSubroutine
+	Body       *Ast
+}
+
+// checkScope mirrors Scope for the checker.
+type checkScope struct {
+	Variables  map[string]*checkType
+	Main       bool
+	Subroutine *checkSubroutine
+	Global     *checkScope
+	Parent     *checkScope
+}
+
+// builtinSignatures are the types of builtinFunctions.
+// They should be kept the same as the functions.
+var builtinSignatures = map[string]struct {
+	Params     []*checkType
+	ReturnType *checkType
+}{
+	"ASC":   {[]*checkType{checkChar}, checkInt},
+	"CHR":   {[]*checkType{checkInt}, checkChar},
+	"INT":   {[]*checkType{checkReal}, checkInt},
+	"RAND":  {[]*checkType{checkInt}, checkReal},
+	"ROUND": {[]*checkType{checkReal, checkInt}, checkReal},
+}
+
+// Check checks the ast and gives all the errors sorted by position.
+// The ast should be compiled only if there are no errors.
+func Check(ast *Ast) Diagnostics {
+	runtime := NewGlobalScope()
+	runtime.InitRuntime()
+	c := &Checker{
+		types:       make(map[string]*checkRecord),
+		subroutines: make(map[string]*checkSubroutine),
+		globals:     make(map[string]bool),
+		runtime:     runtime,
+	}
+
+	globalScope := &checkScope{Variables: make(map[string]*checkType)}
+	globalScope.Global = globalScope
+	mainScope := globalScope.newScope()
+	mainScope.Main = true
+	c.declare(ast)
+	c.checkAst(ast, mainScope)
+	// Like CompileSubroutines, bodies are checked after the main block,
+	// so that all the global variables are available.
+	for _, body := range c.bodies {
+		c.checkBody(body, globalScope)
+	}
+
+	sort.SliceStable(c.Errors, func(i, j int) bool {
+		if c.Errors[i].Line != c.Errors[j].Line {
+			return c.Errors[i].Line < c.Errors[j].Line
+		}
+		return c.Errors[i].Column < c.Errors[j].Column
+	})
+	return c.Errors
+}
+
+// errorf records an error at the position.
+// The diagnostic is given so that its span and fix could be set.
+func (c *Checker) errorf(pos lexer.Position, code string, format string, args ...interface{}) *Diagnostic {
+	diagnostic := newDiagnostic(pos, code, fmt.Sprintf(format, args...))
+	c.Errors = append(c.Errors, diagnostic)
+	return diagnostic
+}
+
+// newScope creates a new scope under the given scope.
+func (scope *checkScope) newScope() *checkScope {
+	return &checkScope{
+		Variables:  make(map[string]*checkType),
+		Subroutine: scope.Subroutine,
+		Global:     scope.Global,
+		Parent:     scope,
+	}
+}
+
+// find locates the type of the variable registered.
+func (scope *checkScope) find(name string) (*checkType, bool) {
+	for currentScope := scope; currentScope != nil; currentScope = currentScope.Parent {
+		if varType, ok := currentScope.Variables[name]; ok {
+			return varType, true
+		}
+	}
+	return nil, false
+}
+
+// names gives the names of all the variables which could be found.
+func (scope *checkScope) names() []string {
+	names := []string{}
+	for currentScope := scope; currentScope != nil; currentScope = currentScope.Parent {
+		for name := range currentScope.Variables {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// register registers a variable to the scope.
+// It follows Scope.RegisterVariable.
+func (c *Checker) register(scope *checkScope, pos lexer.Position, name string, varType *checkType) {
+	if _, ok := scope.Variables[name]; ok {
+		c.errorf(pos, codeRedefined, "Define Variable more than once in a scope: %s", name).span(len(name))
+		return
+	}
+	scope.Variables[name] = varType
+}
+
+// declare declares types and subroutines in the main block,
+// so that they could be used before they are defined.
+// It follows Ast.Declare.
+func (c *Checker) declare(ast *Ast) {
+	for _, inst := range ast.Instructions {
+		if inst.DeclareVariable != nil {
+			c.globals[inst.DeclareVariable.Name] = true
+		}
+	}
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.TypeDefinition != nil:
+			c.declareType(inst.TypeDefinition)
+		case inst.Procedure != nil:
+			ins := inst.Procedure
+			c.declareSubroutine(ins.Pos, ins.Name, ins.Params, nil, &ins.Body)
+		case inst.Function != nil:
+			ins := inst.Function
+			if !ins.Body.Returns() {
+				c.errorf(ins.Pos, codeMissingReturn, "Function %s should RETURN at the end of every path", ins.Name).
+					suggest("Add RETURN at the end of function %s", ins.Name)
+			}
+			c.declareSubroutine(ins.Pos, ins.Name, ins.Params, c.resolveType(&ins.ReturnType), &ins.Body)
+		}
+	}
+}
+
+// declareType declares a record type.
+// Fields could only be of types defined before.
+func (c *Checker) declareType(ins *InstTypeDefinition) {
+	record := &checkRecord{Name: ins.Name, Fields: make(map[string]*checkType)}
+	for _, field := range ins.Fields {
+		fieldType := c.resolveType(&field.Type)
+		if _, ok := record.Fields[field.Name]; ok {
+			c.errorf(field.Pos, codeRedefined, "Define Field more than once in a type: %s", field.Name).span(len(field.Name))
+			continue
+		}
+		record.Fields[field.Name] = fieldType
+	}
+	if _, ok := c.types[ins.Name]; ok {
+		c.errorf(ins.Pos, codeRedefined, "Define Type more than once: %s", ins.Name)
+		return
+	}
+	c.types[ins.Name] = record
+}
+
+// declareSubroutine declares a procedure or a function.
+func (c *Checker) declareSubroutine(pos lexer.Position, name string, params []*Parameter, returnType *checkType, body *Ast) {
+	subroutine := &checkSubroutine{Name: name, Params: params, ReturnType: returnType}
+	for _, param := range params {
+		subroutine.ParamTypes = append(subroutine.ParamTypes, c.resolveType(&param.Type))
+	}
+	c.bodies = append(c.bodies, &checkBody{Pos: pos, Subroutine: subroutine, Body: body})
+
+	_, builtin := builtinSignatures[name]
+	_, defined := c.subroutines[name]
+	switch {
+	case builtin:
+		c.errorf(pos, codeRedefined, "%s is a built-in function", name)
+	case defined || name == "main" || c.runtime.FindFunction(name) != nil:
+		c.errorf(pos, codeRedefined, "Define Function more than once: %s", name)
+	case c.globals[name]:
+		c.errorf(pos, codeRedefined, "%s is already a global variable", name)
+	default:
+		c.subroutines[name] = subroutine
+	}
+}
+
+// checkBody checks the body of a subroutine.
+// Parameters and variables of the body are in the same scope.
+func (c *Checker) checkBody(body *checkBody, globalScope *checkScope) {
+	funcScope := globalScope.newScope()
+	funcScope.Subroutine = body.Subroutine
+	for index, param := range body.Subroutine.Params {
+		c.register(funcScope, param.Pos, param.Name, body.Subroutine.ParamTypes[index])
+	}
+	c.checkAst(body.Body, funcScope)
+}
+
+// resolveType gives the type declared.
+func (c *Checker) resolveType(t *VariableType) *checkType {
+	switch {
+	case t.ARRAY != nil:
+		for _, dimension := range t.ARRAY.Dimensions {
+			if dimension.Upper.Int() < dimension.Lower.Int() {
+				c.errorf(dimension.Pos, codeInvalidType, "Upper bound of array is less than lower bound")
+				return checkInvalid
+			}
+		}
+		return &checkType{Kind: arrayKind, Dimensions: t.ARRAY.Dimensions, Element: c.resolveType(t.ARRAY.Element)}
+	case t.Int != nil:
+		return checkInt
+	case t.REAL != nil:
+		return checkReal
+	case t.BOOL != nil:
+		return checkBool
+	case t.CHAR != nil:
+		return checkChar
+	case t.STRING != nil:
+		return checkString
+	case t.DATE != nil:
+		return checkDate
+	case t.CUSTOM != nil:
+		record, ok := c.types[*t.CUSTOM]
+		if !ok {
+			diagnostic := c.errorf(t.Pos, codeUnknownType, "Unknown type %s", *t.CUSTOM).span(len(*t.CUSTOM))
+			names := []string{}
+			for name := range c.types {
+				names = append(names, name)
+			}
+			if name, ok := closest(*t.CUSTOM, names); ok {
+				diagnostic.suggest("Did you mean %s?", name)
+			}
+			return checkInvalid
+		}
+		return &checkType{Kind: recordKind, Record: record}
+	}
+	return checkInvalid
+}
+
+// checkAst checks the instructions in the scope.
+// It follows Ast.Compile.
+func (c *Checker) checkAst(ast *Ast, scope *checkScope) {
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.Output != nil:
+			for _, item := range inst.Output.Items {
+				if itemType := c.expression(item, scope); !itemType.Scalar() {
+					c.errorf(item.Pos, codeTypeMismatch, "Cannot OUTPUT a value of type %s", itemType)
+				}
+			}
+		case inst.Input != nil:
+			if keyType := c.key(&inst.Input.Content, scope); !keyType.Scalar() {
+				c.errorf(inst.Input.Pos, codeTypeMismatch, "Cannot INPUT into a variable of type %s", keyType)
+			}
+		case inst.Call != nil:
+			if inst.Call.Name != nil {
+				c.call(&FunctionCall{Pos: inst.Call.Pos, Name: *inst.Call.Name}, scope, false)
+			} else {
+				c.call(inst.Call.Function, scope, false)
+			}
+		case inst.DeclareVariable != nil:
+			c.checkDeclare(inst.DeclareVariable, scope)
+		case inst.TypeDefinition != nil, inst.Procedure != nil, inst.Function != nil:
+			if !scope.Main {
+				c.errorf(inst.Pos, codeMisplaced, "Types, procedures and functions should be defined in the main block").
+					suggest("Move it out of the block")
+			}
+		case inst.Return != nil:
+			c.checkReturn(inst.Return, scope)
+		case inst.Assignment != nil:
+			ins := inst.Assignment
+			keyType := c.key(&ins.Left, scope)
+			valueType := c.expression(&ins.Right, scope)
+			if !keyType.Accepts(valueType) {
+				diagnostic := c.errorf(ins.Pos, codeTypeMismatch, "Cannot assign %s to %s", valueType, keyType)
+				if keyType.Kind == intKind && valueType.Kind == realKind {
+					diagnostic.suggest("Use INT() to convert REAL to INT")
+				}
+			}
+		case inst.ConditionBr != nil:
+			ins := inst.ConditionBr
+			c.condition(&ins.Condition, scope, "IF")
+			c.checkAst(&ins.TrueBr, scope.newScope())
+			if ins.FalseBr != nil {
+				c.checkAst(ins.FalseBr, scope.newScope())
+			}
+		case inst.While != nil:
+			c.condition(&inst.While.Condition, scope.newScope(), "WHILE")
+			c.checkAst(&inst.While.Body, scope.newScope())
+		case inst.Repeat != nil:
+			// The condition could use variables declared in the body.
+			bodyScope := scope.newScope()
+			c.checkAst(&inst.Repeat.Body, bodyScope)
+			c.condition(&inst.Repeat.Condition, bodyScope, "UNTIL")
+		case inst.For != nil:
+			c.checkFor(inst.For, scope)
+		case inst.Case != nil:
+			c.checkCase(inst.Case, scope)
+		}
+	}
+}
+
+// checkDeclare declares the variable.
+// Variables in the main block are global.
+func (c *Checker) checkDeclare(ins *InstDeclareVariable, scope *checkScope) {
+	varType := c.resolveType(&ins.Type)
+	if scope.Main {
+		scope = scope.Global
+	}
+	c.register(scope, ins.Pos, ins.Name, varType)
+}
+
+// checkReturn checks RETURN is in a function and returns the right type.
+func (c *Checker) checkReturn(ins *InstReturn, scope *checkScope) {
+	valueType := c.expression(&ins.Value, scope)
+	if scope.Subroutine == nil || scope.Subroutine.ReturnType == nil {
+		c.errorf(ins.Pos, codeMisplaced, "RETURN should be in a function")
+		return
+	}
+	if !scope.Subroutine.ReturnType.Accepts(valueType) {
+		c.errorf(ins.Pos, codeTypeMismatch, "RETURN %s from function %s which returns %s", valueType, scope.Subroutine.Name, scope.Subroutine.ReturnType)
+	}
+}
+
+// condition checks the condition is a BOOL.
+func (c *Checker) condition(e *Expression, scope *checkScope, name string) {
+	if condType := c.expression(e, scope); !condType.Is(boolKind) {
+		c.errorf(e.Pos, codeTypeMismatch, "Condition of %s should be BOOL, not %s", name, condType)
+	}
+}
+
+// checkFor checks the counter and the bounds of FOR.
+func (c *Checker) checkFor(ins *InstFor, scope *checkScope) {
+	if ins.Next != nil && *ins.Next != ins.Counter {
+		c.errorf(ins.Pos, codeNextMismatch, "NEXT %s does not match FOR %s", *ins.Next, ins.Counter).
+			suggest("Use NEXT %s", ins.Counter)
+	}
+	counterType := c.variable(&Variable{Pos: ins.Pos, Name: ins.Counter}, scope)
+	if !counterType.Numeric() {
+		c.errorf(ins.Pos, codeTypeMismatch, "Counter of FOR should be INT or REAL")
+		counterType = checkInvalid
+	}
+	bounds := []*Expression{&ins.Start, &ins.End}
+	if ins.Step != nil {
+		bounds = append(bounds, ins.Step)
+	}
+	for _, bound := range bounds {
+		if boundType := c.expression(bound, scope); !counterType.Accepts(boundType) {
+			c.errorf(bound.Pos, codeTypeMismatch, "Bounds of FOR should be of the same type as the counter")
+		}
+	}
+	c.checkAst(&ins.Body, scope.newScope())
+}
+
+// checkCase checks the labels of CASE are of the type of the value.
+func (c *Checker) checkCase(ins *InstCase, scope *checkScope) {
+	valueType := c.expression(&ins.Value, scope)
+	if !valueType.Scalar() {
+		c.errorf(ins.Value.Pos, codeTypeMismatch, "Cannot use a value of type %s in CASE", valueType)
+		valueType = checkInvalid
+	}
+	// labels are the ones of the right type, which are checked against the following labels.
+	labels := []*CaseLabel{}
+	for _, clause := range ins.Clauses {
+		for _, label := range clause.Labels {
+			labelValues := []*CaseValue{&label.From}
+			if label.To != nil {
+				labelValues = append(labelValues, label.To)
+				if valueType.Kind == boolKind {
+					c.errorf(label.Pos, codeTypeMismatch, "Cannot use a range of BOOL in CASE")
+				}
+			}
+			accepted := true
+			for _, labelValue := range labelValues {
+				labelType := c.caseValue(labelValue)
+				if !labelType.Valid() {
+					accepted = false
+				}
+				if !valueType.Accepts(labelType) {
+					c.errorf(label.Pos, codeTypeMismatch, "Label of CASE should be of the same type as the value")
+					accepted = false
+				}
+			}
+			if !accepted || valueType == checkInvalid {
+				continue
+			}
+			for _, previous := range labels {
+				if label.Overlaps(previous) {
+					c.errorf(label.Pos, codeDuplicateCase, "Label of CASE overlaps the one at line %d", previous.Pos.Line).
+						suggest("Remove the label or make the ranges disjoint")
+					break
+				}
+			}
+			labels = append(labels, label)
+		}
+		c.checkAst(&clause.Body, scope.newScope())
+	}
+	if ins.Otherwise != nil {
+		c.checkAst(ins.Otherwise, scope.newScope())
+	}
+}
+
+// caseValue gives the type of a label of CASE.
+func (c *Checker) caseValue(v *CaseValue) *checkType {
+	valueType := c.constant(&v.Constant)
+	if v.Negative && !valueType.Numeric() {
+		c.errorf(v.Pos, codeTypeMismatch, "Only INT and REAL could be negative")
+		return checkInvalid
+	}
+	return valueType
+}
+
+// key gives the type of the key.
+// It follows Key.Locate.
+func (c *Checker) key(key *Key, scope *checkScope) *checkType {
+	keyType := c.variable(key.Variables[0], scope)
+	for _, field := range key.Variables[1:] {
+		keyType = c.field(field, keyType, scope)
+	}
+	return keyType
+}
+
+// variable gives the type of the variable with its indices applied.
+func (c *Checker) variable(v *Variable, scope *checkScope) *checkType {
+	varType, ok := scope.find(v.Name)
+	if !ok {
+		diagnostic := c.errorf(v.Pos, codeUndeclared, "Variable not declared: %s", v.Name).span(len(v.Name))
+		if name, ok := closest(v.Name, scope.names()); ok {
+			diagnostic.suggest("Did you mean %s?", name)
+		} else {
+			diagnostic.suggest("Declare it with DECLARE %s before using it", v.Name)
+		}
+		varType = checkInvalid
+	}
+	return c.index(v, varType, scope)
+}
+
+// field gives the type of the field of a record with its indices applied.
+func (c *Checker) field(v *Variable, recordType *checkType, scope *checkScope) *checkType {
+	fieldType := checkInvalid
+	switch {
+	case !recordType.Valid():
+	case recordType.Kind != recordKind:
+		c.errorf(v.Pos, codeNoField, "Not a record to get field %s", v.Name)
+	default:
+		var ok bool
+		if fieldType, ok = recordType.Record.Fields[v.Name]; !ok {
+			diagnostic := c.errorf(v.Pos, codeNoField, "%s has no field %s", recordType.Record.Name, v.Name).span(len(v.Name))
+			names := []string{}
+			for name := range recordType.Record.Fields {
+				names = append(names, name)
+			}
+			if name, ok := closest(v.Name, names); ok {
+				diagnostic.suggest("Did you mean %s?", name)
+			}
+			fieldType = checkInvalid
+		}
+	}
+	return c.index(v, fieldType, scope)
+}
+
+// index gives the type of the element if indices are given.
+func (c *Checker) index(v *Variable, varType *checkType, scope *checkScope) *checkType {
+	if len(v.Indices) == 0 {
+		return varType
+	}
+	for _, index := range v.Indices {
+		if indexType := c.expression(index, scope); !indexType.Is(intKind) {
+			c.errorf(index.Pos, codeTypeMismatch, "Index of array should be INT")
+		}
+	}
+	switch {
+	case !varType.Valid():
+		return checkInvalid
+	case varType.Kind != arrayKind:
+		c.errorf(v.Pos, codeIndex, "%s is not an array", v.Name).span(len(v.Name))
+		return checkInvalid
+	case len(v.Indices) != len(varType.Dimensions):
+		c.errorf(v.Pos, codeIndex, "%s has %d dimensions but %d indices are given", v.Name, len(varType.Dimensions), len(v.Indices))
+		return checkInvalid
+	}
+	return varType.Element
+}
+
+// call checks the arguments of the call and gives the type it returns.
+// value tells if the call is a part of an expression.
+func (c *Checker) call(f *FunctionCall, scope *checkScope, value bool) *checkType {
+	var returnType *checkType
+	if signature, ok := builtinSignatures[f.Name]; ok {
+		returnType = signature.ReturnType
+		c.arity(f, len(signature.Params), false)
+		c.arguments(f, scope, nil, signature.Params)
+	} else if subroutine, ok := c.subroutines[f.Name]; ok {
+		returnType = subroutine.ReturnType
+		if returnType == nil {
+			returnType = checkVoid
+		}
+		c.arity(f, len(subroutine.Params), false)
+		c.arguments(f, scope, subroutine.Params, subroutine.ParamTypes)
+	} else if function := c.runtime.FindCFunction(f.Name); function != nil {
+		returnType = irCheckType(function.Sig.RetType)
+		c.arity(f, len(function.Sig.Params), function.Sig.Variadic)
+		c.cArguments(f, scope, function.Sig.Params)
+	} else {
+		diagnostic := c.errorf(f.Pos, codeUndeclared, "Function not defined: %s", f.Name).span(len(f.Name))
+		names := []string{}
+		for name := range builtinSignatures {
+			names = append(names, name)
+		}
+		for name := range c.subroutines {
+			names = append(names, name)
+		}
+		if name, ok := closest(f.Name, names); ok {
+			diagnostic.suggest("Did you mean %s?", name)
+		}
+		c.arguments(f, scope, nil, nil)
+		return checkInvalid
+	}
+
+	if value && returnType.Kind == voidKind {
+		c.errorf(f.Pos, codeNoValue, "%s does not return a value", f.Name).span(len(f.Name))
+		return checkInvalid
+	}
+	return returnType
+}
+
+// cArguments checks the arguments of a function of C against the types of its parameters.
+// Arguments after params are the variadic ones.
+// It follows cArgumentEval.
+func (c *Checker) cArguments(f *FunctionCall, scope *checkScope, params []types.Type) {
+	for index, argument := range f.Params {
+		var paramType types.Type
+		if index < len(params) {
+			paramType = params[index]
+		}
+		kinds := cParamKinds(paramType)
+		argumentType := c.expression(argument, scope)
+		if !argumentType.Valid() {
+			continue
+		}
+		accepted := false
+		names := make([]string, len(kinds))
+		for i, kind := range kinds {
+			accepted = accepted || argumentType.Kind == kind
+			names[i] = (&checkType{Kind: kind}).String()
+		}
+		if !accepted {
+			expected := strings.Join(names, ", ")
+			if len(names) > 1 {
+				expected = strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+			}
+			c.errorf(argument.Pos, codeTypeMismatch, "Argument %d of %s should be %s, not %s", index+1, f.Name, expected, argumentType)
+		}
+	}
+}
+
+// arity checks the number of arguments.
+// More arguments are allowed if the function is variadic.
+func (c *Checker) arity(f *FunctionCall, count int, variadic bool) {
+	if len(f.Params) < count || (!variadic && len(f.Params) > count) {
+		c.errorf(f.Pos, codeArguments, "%s takes %d arguments but %d are given", f.Name, count, len(f.Params))
+	}
+}
+
+// arguments checks the arguments against the types of parameters.
+// It follows passArguments if params are given.
+func (c *Checker) arguments(f *FunctionCall, scope *checkScope, params []*Parameter, paramTypes []*checkType) {
+	for index, argument := range f.Params {
+		if params != nil && index < len(params) && params[index].BYREF {
+			key := argument.Key()
+			if key == nil {
+				c.errorf(argument.Pos, codeArguments, "Argument %s of %s is BYREF and should be a variable", params[index].Name, f.Name).
+					suggest("Pass a variable, or declare %s BYVAL", params[index].Name)
+				c.expression(argument, scope)
+			} else if !paramTypes[index].Equal(c.key(key, scope)) {
+				c.errorf(argument.Pos, codeTypeMismatch, "Argument %s of %s has a wrong type", params[index].Name, f.Name)
+			}
+			continue
+		}
+		argumentType := c.expression(argument, scope)
+		if paramTypes != nil && index < len(paramTypes) && !paramTypes[index].Accepts(argumentType) {
+			c.errorf(argument.Pos, codeTypeMismatch, "Argument %d of %s should be %s, not %s", index+1, f.Name, paramTypes[index], argumentType)
+		}
+	}
+}
+
+// expression infers the type of the expression.
+func (c *Checker) expression(e *Expression, scope *checkScope) *checkType {
+	d := &e.Disjunction
+	exprType := c.conjunction(&d.Head, scope)
+	for _, item := range d.Items {
+		exprType = c.logic(d.Pos, "OR", exprType, c.conjunction(item, scope))
+	}
+	return exprType
+}
+
+// conjunction infers the type of AND.
+func (c *Checker) conjunction(conj *Conjunction, scope *checkScope) *checkType {
+	exprType := c.negation(&conj.Head, scope)
+	for _, item := range conj.Items {
+		exprType = c.logic(conj.Pos, "AND", exprType, c.negation(item, scope))
+	}
+	return exprType
+}
+
+// logic checks both sides of AND and OR are BOOLs.
+func (c *Checker) logic(pos lexer.Position, operator string, lhsType *checkType, rhsType *checkType) *checkType {
+	if !lhsType.Is(boolKind) || !rhsType.Is(boolKind) {
+		c.errorf(pos, codeTypeMismatch, "%s takes BOOLs", operator)
+	}
+	return checkBool
+}
+
+// negation infers the type of NOT.
+func (c *Checker) negation(n *Negation, scope *checkScope) *checkType {
+	if n.Not == nil {
+		return c.comparison(n.Comparison, scope)
+	}
+	if !c.negation(n.Not, scope).Is(boolKind) {
+		c.errorf(n.Pos, codeTypeMismatch, "NOT takes a BOOL")
+	}
+	return checkBool
+}
+
+// comparison infers the type of comparisons.
+func (c *Checker) comparison(comp *Comparison, scope *checkScope) *checkType {
+	exprType := c.addition(&comp.Head, scope)
+	for _, item := range comp.Items {
+		exprType = c.operation(item.Pos, item.Operator, exprType, c.addition(&item.Item, scope))
+	}
+	return exprType
+}
+
+// addition infers the type of + and -.
+func (c *Checker) addition(a *Addition, scope *checkScope) *checkType {
+	exprType := c.multiplication(&a.Head, scope)
+	for _, item := range a.Items {
+		exprType = c.operation(item.Pos, item.Operator, exprType, c.multiplication(&item.Item, scope))
+	}
+	return exprType
+}
+
+// multiplication infers the type of *, /, DIV and MOD.
+func (c *Checker) multiplication(m *Multiplication, scope *checkScope) *checkType {
+	exprType := c.unary(&m.Head, scope)
+	for _, item := range m.Items {
+		exprType = c.operation(item.Pos, item.Operator, exprType, c.unary(&item.Item, scope))
+	}
+	return exprType
+}
+
+// operation infers the type of a binary operation.
+// It follows the functions in operation.go.
+func (c *Checker) operation(pos lexer.Position, operator string, lhsType *checkType, rhsType *checkType) *checkType {
+	if !lhsType.Valid() || !rhsType.Valid() {
+		return checkInvalid
+	}
+	unified := unifyTypes(lhsType, rhsType)
+	switch operator {
+	case "/":
+		if lhsType.Numeric() && rhsType.Numeric() {
+			return checkReal
+		}
+	case "DIV", "MOD":
+		if lhsType.Kind == intKind && rhsType.Kind == intKind {
+			return checkInt
+		}
+	case "+", "-", "*":
+		if unified != nil && unified.Numeric() {
+			return unified
+		}
+	case "=", "<>":
+		if unified != nil && unified.Scalar() {
+			return checkBool
+		}
+	default:
+		if unified != nil && unified.Scalar() && unified.Kind != boolKind {
+			return checkBool
+		}
+	}
+	c.errorf(pos, codeTypeMismatch, "Cannot apply %s to %s and %s", operator, lhsType, rhsType)
+	return checkInvalid
+}
+
+// unary infers the type of unary operations.
+func (c *Checker) unary(u *Unary, scope *checkScope) *checkType {
+	if u.Opposite == nil {
+		return c.primary(u.Primary, scope)
+	}
+	operandType := c.unary(u.Opposite, scope)
+	if !operandType.Numeric() {
+		c.errorf(u.Pos, codeTypeMismatch, "Cannot apply - to %s", operandType)
+		return checkInvalid
+	}
+	return operandType
+}
+
+// primary infers the type of the primary.
+func (c *Checker) primary(p *Primary, scope *checkScope) *checkType {
+	switch {
+	case p.Constant != nil:
+		return c.constant(p.Constant)
+	case p.Key != nil:
+		return c.key(p.Key, scope)
+	case p.Function != nil:
+		return c.call(p.Function, scope, true)
+	case p.Subexpression != nil:
+		return c.expression(p.Subexpression, scope)
+	}
+	return checkInvalid
+}
+
+// constant gives the type of the constant.
+func (c *Checker) constant(constant *Constant) *checkType {
+	switch {
+	case constant.VBool != nil:
+		return checkBool
+	case constant.VString != nil:
+		return checkString
+	case constant.VChar != nil:
+		if _, ok := constant.Char(); !ok {
+			c.errorf(constant.Pos, codeInvalidChar, "CHAR should be a single byte: '%s'", *constant.VChar).
+				suggest("Use a STRING for characters of more than one byte")
+			return checkInvalid
+		}
+		return checkChar
+	case constant.VReal != nil:
+		return checkReal
+	case constant.VInt != nil:
+		return checkInt
+	}
+	return checkInvalid
+}
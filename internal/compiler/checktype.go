@@ -0,0 +1,195 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/llvm/ir/types"
+)
+
+// checkKind is the kind of a type known to the checker.
+type checkKind int
+
+const (
+	// invalidKind is the type of anything with errors reported already.
+	// It matches every type, so that an error is not reported again and again.
+	invalidKind checkKind = iota
+	intKind
+	realKind
+	boolKind
+	charKind
+	stringKind
+	dateKind
+	arrayKind
+	recordKind
+	// voidKind is the type of calls to procedures.
+	voidKind
+)
+
+// checkType is the type of a variable or an expression for the checker.
+// It is the same as the type given by the compiler,
+// but it could be inferred without generating IR.
+type checkType struct {
+	Kind checkKind
+	// Dimensions and Element are given for arrays.
+	Dimensions []*ArrayDimension
+	Element    *checkType
+	// Record is given for records.
+	Record *checkRecord
+}
+
+// checkRecord is a record type defined by TYPE.
+type checkRecord struct {
+	Name   string
+	Fields map[string]*checkType
+}
+
+var (
+	checkInvalid = &checkType{Kind: invalidKind}
+	checkInt     = &checkType{Kind: intKind}
+	checkReal    = &checkType{Kind: realKind}
+	checkBool    = &checkType{Kind: boolKind}
+	checkChar    = &checkType{Kind: charKind}
+	checkString  = &checkType{Kind: stringKind}
+	checkDate    = &checkType{Kind: dateKind}
+	checkVoid    = &checkType{Kind: voidKind}
+)
+
+// String gives the name of the type in pseudocode for messages.
+func (t *checkType) String() string {
+	switch t.Kind {
+	case intKind:
+		return "INT"
+	case realKind:
+		return "REAL"
+	case boolKind:
+		return "BOOL"
+	case charKind:
+		return "CHAR"
+	case stringKind:
+		return "STRING"
+	case dateKind:
+		return "DATE"
+	case arrayKind:
+		dimensions := make([]string, len(t.Dimensions))
+		for index, dimension := range t.Dimensions {
+			dimensions[index] = fmt.Sprintf("%d:%d", dimension.Lower.Int(), dimension.Upper.Int())
+		}
+		return "ARRAY[" + strings.Join(dimensions, ", ") + "] OF " + t.Element.String()
+	case recordKind:
+		return t.Record.Name
+	case voidKind:
+		return "nothing"
+	}
+	return "unknown"
+}
+
+// Valid checks if the type has no errors.
+func (t *checkType) Valid() bool {
+	return t.Kind != invalidKind
+}
+
+// Numeric checks if the type is INT or REAL.
+func (t *checkType) Numeric() bool {
+	return t.Kind == intKind || t.Kind == realKind || !t.Valid()
+}
+
+// Scalar checks if the type could be input, output and compared.
+func (t *checkType) Scalar() bool {
+	return t.Kind != arrayKind && t.Kind != recordKind && t.Kind != voidKind
+}
+
+// Is checks if the type is of the kind.
+// Invalid types are of every kind.
+func (t *checkType) Is(kind checkKind) bool {
+	return t.Kind == kind || !t.Valid()
+}
+
+// Equal checks if two types are the same.
+func (t *checkType) Equal(u *checkType) bool {
+	if !t.Valid() || !u.Valid() {
+		return true
+	}
+	if t.Kind != u.Kind {
+		return false
+	}
+	switch t.Kind {
+	case arrayKind:
+		if len(t.Dimensions) != len(u.Dimensions) {
+			return false
+		}
+		for index, dimension := range t.Dimensions {
+			if dimension.Lower.Int() != u.Dimensions[index].Lower.Int() ||
+				dimension.Upper.Int() != u.Dimensions[index].Upper.Int() {
+				return false
+			}
+		}
+		return t.Element.Equal(u.Element)
+	case recordKind:
+		return t.Record == u.Record
+	}
+	return true
+}
+
+// Accepts checks if a value of type u could be stored as type t.
+// It follows ConvertEval, which converts INT to REAL only.
+func (t *checkType) Accepts(u *checkType) bool {
+	if t.Kind == realKind && u.Kind == intKind {
+		return true
+	}
+	return t.Equal(u)
+}
+
+// unifyTypes gives the type of both sides of an operation.
+// It follows unifyEval, which converts INT to REAL only.
+// nil would be returned if the types are incompatible.
+func unifyTypes(t *checkType, u *checkType) *checkType {
+	switch {
+	case !t.Valid() || !u.Valid():
+		return checkInvalid
+	case t.Kind == intKind && u.Kind == realKind, t.Kind == realKind && u.Kind == intKind:
+		return checkReal
+	case t.Equal(u):
+		return t
+	}
+	return nil
+}
+
+// cParamKinds gives the kinds of values which could be passed to a parameter of C,
+// whose type is nil for variadic arguments. It follows cArgumentEval:
+// STRINGs are passed as char*, and CHARs and BOOLs are promoted to int like C does.
+func cParamKinds(typ types.Type) []checkKind {
+	switch {
+	case typ == nil:
+		return []checkKind{intKind, realKind, charKind, boolKind, stringKind}
+	case types.Equal(typ, types.I8Ptr):
+		return []checkKind{stringKind}
+	case typ == types.I32:
+		return []checkKind{intKind, charKind, boolKind}
+	case typ == types.Double:
+		return []checkKind{intKind, realKind}
+	}
+	return nil
+}
+
+// irCheckType gives the type of a value given by the runtime.
+// Types which could not be used in pseudocode are invalid.
+func irCheckType(typ types.Type) *checkType {
+	switch {
+	case typ == types.I32:
+		return checkInt
+	case typ == types.Double:
+		return checkReal
+	case typ == types.I1:
+		return checkBool
+	case typ == types.I8:
+		return checkChar
+	case types.Equal(typ, stringPtrType):
+		return checkString
+	case types.Equal(typ, dateType):
+		return checkDate
+	case types.IsVoid(typ):
+		return checkVoid
+	}
+	return checkInvalid
+}
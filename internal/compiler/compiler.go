@@ -6,8 +6,19 @@ import (
 	"github.com/llir/llvm/ir/types"
 )
 
+// symbolPrefix is added to the LLVM names of global variables and subroutines,
+// so that they never clash with each other, main or the runtime.
+const symbolPrefix = "pse."
+
 // Compile compiles the ast.
-func Compile(ast *Ast) *ir.Module {
+// The ast is checked first, and all the errors are given as Diagnostics.
+// Otherwise the error would be a Diagnostic if it could not be compiled.
+func Compile(ast *Ast) (module *ir.Module, err error) {
+	if diagnostics := Check(ast); len(diagnostics) != 0 {
+		return nil, diagnostics
+	}
+	defer recoverDiagnostic(&err)
+
 	globalScope := NewGlobalScope()
 	globalScope.InitRuntime()
 
@@ -16,9 +27,12 @@ func Compile(ast *Ast) *ir.Module {
 
 	mainScope := globalScope.NewFuncScope(main)
 	mainScope.Main = true
+	ast.Declare(mainScope)
 	ast.Compile(mainScope)
 
 	zero := constant.NewInt(types.I32, 0)
 	mainScope.Block.NewRet(zero)
-	return globalScope.Module
+
+	ast.CompileSubroutines(globalScope)
+	return globalScope.Module, nil
 }
@@ -0,0 +1,229 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// SeverityError is the severity of diagnostics which stop compiling.
+const SeverityError = "error"
+
+// Codes tell the kinds of diagnostics for tools.
+const (
+	codeSyntax        = "syntax"
+	codeUndeclared    = "undeclared"
+	codeRedefined     = "redefined"
+	codeUnknownType   = "unknown-type"
+	codeInvalidType   = "invalid-type"
+	codeTypeMismatch  = "type-mismatch"
+	codeArguments     = "arguments"
+	codeNoValue       = "no-value"
+	codeNoField       = "no-field"
+	codeIndex         = "index"
+	codeMisplaced     = "misplaced"
+	codeMissingReturn = "missing-return"
+	codeNextMismatch  = "next-mismatch"
+	codeDuplicateCase = "duplicate-case"
+	codeInvalidChar   = "invalid-char"
+	codeCompile       = "compile"
+)
+
+// Diagnostic is an error in the pseudocode with its position.
+// The end is the position after the code it points to,
+// which is the same as the start if the length is unknown.
+type Diagnostic struct {
+	Severity  string `json:"severity"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	// Fix suggests how to fix it, which might be empty.
+	Fix string `json:"fix,omitempty"`
+}
+
+// newDiagnostic creates an error at the position.
+func newDiagnostic(pos lexer.Position, code string, message string) *Diagnostic {
+	return &Diagnostic{
+		Severity:  SeverityError,
+		Code:      code,
+		Message:   message,
+		File:      pos.Filename,
+		Line:      pos.Line,
+		Column:    pos.Column,
+		EndLine:   pos.Line,
+		EndColumn: pos.Column,
+	}
+}
+
+// span sets the end of the diagnostic by the length of the code on the line.
+func (d *Diagnostic) span(length int) *Diagnostic {
+	d.EndLine = d.Line
+	d.EndColumn = d.Column + length
+	return d
+}
+
+// suggest sets the fix of the diagnostic.
+func (d *Diagnostic) suggest(format string, args ...interface{}) *Diagnostic {
+	d.Fix = fmt.Sprintf(format, args...)
+	return d
+}
+
+func (d *Diagnostic) Error() string {
+	file := d.File
+	if file == "" {
+		file = "<source>"
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", file, d.Line, d.Column, d.Message)
+}
+
+// Diagnostics are all the errors found in the pseudocode.
+type Diagnostics []*Diagnostic
+
+func (d Diagnostics) Error() string {
+	messages := make([]string, len(d))
+	for index, diagnostic := range d {
+		messages[index] = diagnostic.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Print prints the diagnostic with the line of source it points to.
+//
+// Example:
+// 	test.pse:3:7: error: Variable not declared: b
+// 	a <- b + 1
+// 	     ^
+func (d *Diagnostic) Print(w io.Writer, source []byte) {
+	file := d.File
+	if file == "" {
+		file = "<source>"
+	}
+	fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", file, d.Line, d.Column, d.Severity, d.Message)
+	defer func() {
+		if d.Fix != "" {
+			fmt.Fprintf(w, "note: %s\n", d.Fix)
+		}
+	}()
+	lines := strings.Split(string(source), "\n")
+	if d.Line < 1 || d.Line > len(lines) {
+		return
+	}
+	line := strings.TrimRight(lines[d.Line-1], "\r")
+	fmt.Fprintln(w, line)
+	// Tabs are kept, so that the caret is under the column.
+	var caret strings.Builder
+	for index, r := range []rune(line) {
+		if index >= d.Column-1 {
+			break
+		}
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+	fmt.Fprintln(w, caret.String())
+}
+
+// Print prints all the diagnostics and the number of them.
+func (d Diagnostics) Print(w io.Writer, source []byte) {
+	for _, diagnostic := range d {
+		diagnostic.Print(w, source)
+	}
+	if len(d) == 1 {
+		fmt.Fprintln(w, "1 error generated.")
+	} else {
+		fmt.Fprintf(w, "%d errors generated.\n", len(d))
+	}
+}
+
+// AsDiagnostics gives the diagnostics in the error given by Parse or Compile.
+// ok is false if the error is not about the pseudocode.
+func AsDiagnostics(err error) (diagnostics Diagnostics, ok bool) {
+	switch err := err.(type) {
+	case Diagnostics:
+		return err, true
+	case *Diagnostic:
+		return Diagnostics{err}, true
+	}
+	return nil, false
+}
+
+// PrintError prints the error given by Parse or Compile.
+// Diagnostics are printed with the source.
+func PrintError(w io.Writer, err error, source []byte) {
+	if diagnostics, ok := AsDiagnostics(err); ok {
+		diagnostics.Print(w, source)
+		return
+	}
+	fmt.Fprintf(w, "error: %s\n", err)
+}
+
+// closest gives the name most like the misspelled one.
+// ok is false if none of them is close enough to be a typo.
+func closest(name string, names []string) (closestName string, ok bool) {
+	// Names are sorted so that the same one is given for ties.
+	sort.Strings(names)
+	best := len(name)/3 + 1
+	for _, candidate := range names {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < best {
+			best = distance
+			closestName, ok = candidate, true
+		}
+	}
+	return closestName, ok
+}
+
+// editDistance gives the Levenshtein distance between two strings.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// fatalf stops compiling with a diagnostic at the position.
+// The diagnostic is recovered by recoverDiagnostic and returned as the error.
+func fatalf(pos lexer.Position, format string, args ...interface{}) {
+	panic(newDiagnostic(pos, codeCompile, fmt.Sprintf(format, args...)))
+}
+
+// recoverDiagnostic recovers the diagnostic given by fatalf into err.
+// It should be deferred by the functions of the API.
+// Other panics are bugs of the compiler, so they are not recovered.
+func recoverDiagnostic(err *error) {
+	if r := recover(); r != nil {
+		diagnostic, ok := r.(*Diagnostic)
+		if !ok {
+			panic(r)
+		}
+		*err = diagnostic
+	}
+}
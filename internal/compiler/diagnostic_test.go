@@ -0,0 +1,40 @@
+package compiler_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// TestDiagnosticPosition checks the lines and columns of a syntax error and errors of the checker.
+func TestDiagnosticPosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		code   string
+		line   int
+		column int
+	}{
+		// The lexer tries Float before Int, which should not move the column of "e3".
+		{"syntax", "DECLARE a : INT\nOUTPUT 1, 2, 3, 1e3\n", "syntax", 2, 18},
+		{"check", "DECLARE a : INT\nIF TRUE\n  THEN\n    a <- 1.5 + b\nENDIF\n", "undeclared", 4, 16},
+		{"char", "OUTPUT 'x', 'é'\n", "invalid-char", 1, 13},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := compiler.Parse(strings.NewReader(test.source))
+			if err == nil {
+				err = compiler.Check(ast)
+			}
+			diagnostics, ok := compiler.AsDiagnostics(err)
+			if !ok || len(diagnostics) != 1 {
+				t.Fatalf("got %v, want one diagnostic", err)
+			}
+			d := diagnostics[0]
+			if d.Code != test.code || d.Line != test.line || d.Column != test.column {
+				t.Errorf("got %s at %d:%d, want %s at %d:%d", d.Code, d.Line, d.Column, test.code, test.line, test.column)
+			}
+		})
+	}
+}
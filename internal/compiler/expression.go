@@ -1,8 +1,7 @@
 package compiler
 
 import (
-	"log"
-
+	"github.com/alecthomas/participle/lexer"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
@@ -14,7 +13,55 @@ import (
 
 // Evaluate evalues the expression and generates IR.
 func (e *Expression) Evaluate(scope *Scope) value.Value {
-	return e.Comparison.Evaluate(scope)
+	return e.Disjunction.Evaluate(scope)
+}
+
+// Key gives the key if the expression is nothing but a key.
+// Otherwise nil would be returned.
+func (e *Expression) Key() *Key {
+	disjunction := e.Disjunction
+	if len(disjunction.Items) != 0 || len(disjunction.Head.Items) != 0 {
+		return nil
+	}
+	comparison := disjunction.Head.Head.Comparison
+	if comparison == nil || len(comparison.Items) != 0 || len(comparison.Head.Items) != 0 {
+		return nil
+	}
+	multiplication := comparison.Head.Head
+	if len(multiplication.Items) != 0 || multiplication.Head.Primary == nil {
+		return nil
+	}
+	return multiplication.Head.Primary.Key
+}
+
+// Evaluate evalutes disjunction
+func (d *Disjunction) Evaluate(scope *Scope) value.Value {
+	lhsValue := d.Head.Evaluate(scope)
+	for _, item := range d.Items {
+		lhsValue = shortCircuitEval(scope, d.Pos, "OR", lhsValue, item.Evaluate)
+	}
+	return lhsValue
+}
+
+// Evaluate evalutes conjunction
+func (c *Conjunction) Evaluate(scope *Scope) value.Value {
+	lhsValue := c.Head.Evaluate(scope)
+	for _, item := range c.Items {
+		lhsValue = shortCircuitEval(scope, c.Pos, "AND", lhsValue, item.Evaluate)
+	}
+	return lhsValue
+}
+
+// Evaluate evalutes negation
+func (n *Negation) Evaluate(scope *Scope) value.Value {
+	if n.Not != nil {
+		operand := n.Not.Evaluate(scope)
+		if operand.Type() != types.I1 {
+			fatalf(n.Pos, "NOT takes a BOOL")
+		}
+		return NotEval(scope, operand)
+	}
+	return n.Comparison.Evaluate(scope)
 }
 
 // Evaluate evalutes comparison
@@ -29,22 +76,25 @@ func (c *Comparison) Evaluate(scope *Scope) value.Value {
 // Evaluate evalutes op based on lhs
 func (o *OpComparison) Evaluate(scope *Scope, lhsValue value.Value) value.Value {
 	rhsValue := o.Item.Evaluate(scope)
+	var result value.Value
 	switch o.Operator {
 	case "=":
-		return CmpEQEval(scope, lhsValue, rhsValue)
+		result = CmpEQEval(scope, lhsValue, rhsValue)
 	case "<>":
-		return CmpNEEval(scope, lhsValue, rhsValue)
+		result = CmpNEEval(scope, lhsValue, rhsValue)
 	case ">":
-		return CmpGTEval(scope, lhsValue, rhsValue)
+		result = CmpGTEval(scope, lhsValue, rhsValue)
 	case ">=":
-		return CmpGEEval(scope, lhsValue, rhsValue)
+		result = CmpGEEval(scope, lhsValue, rhsValue)
 	case "<":
-		return CmpLTEval(scope, lhsValue, rhsValue)
+		result = CmpLTEval(scope, lhsValue, rhsValue)
 	case "<=":
-		return CmpLEEval(scope, lhsValue, rhsValue)
+		result = CmpLEEval(scope, lhsValue, rhsValue)
 	}
-	log.Fatal("unreachable")
-	return nil
+	if result == nil {
+		operationError(o.Pos, o.Operator, lhsValue, rhsValue)
+	}
+	return result
 }
 
 // Evaluate evalutes addition
@@ -59,14 +109,17 @@ func (a *Addition) Evaluate(scope *Scope) value.Value {
 // Evaluate evalutes op based on lhs
 func (o *OpAddition) Evaluate(scope *Scope, lhsValue value.Value) value.Value {
 	rhsValue := o.Item.Evaluate(scope)
+	var result value.Value
 	switch o.Operator {
 	case "+":
-		return AddEval(scope, lhsValue, rhsValue)
+		result = AddEval(scope, lhsValue, rhsValue)
 	case "-":
-		return MinusEval(scope, lhsValue, rhsValue)
+		result = MinusEval(scope, lhsValue, rhsValue)
 	}
-	log.Fatal("unreachable")
-	return nil
+	if result == nil {
+		operationError(o.Pos, o.Operator, lhsValue, rhsValue)
+	}
+	return result
 }
 
 // Evaluate evaluates multiplication
@@ -81,26 +134,42 @@ func (m *Multiplication) Evaluate(scope *Scope) value.Value {
 // Evaluate evalutes op based on lhs
 func (o *OpMultiplication) Evaluate(scope *Scope, lhsValue value.Value) value.Value {
 	rhsValue := o.Item.Evaluate(scope)
+	var result value.Value
 	switch o.Operator {
 	case "*":
-		return MultipleEval(scope, lhsValue, rhsValue)
+		result = MultipleEval(scope, lhsValue, rhsValue)
 	case "/":
-		return DivideEval(scope, lhsValue, rhsValue)
+		result = DivideEval(scope, lhsValue, rhsValue)
+	case "DIV":
+		result = IntDivideEval(scope, o.Pos.Line, lhsValue, rhsValue)
+	case "MOD":
+		result = ModEval(scope, o.Pos.Line, lhsValue, rhsValue)
 	}
-	return nil
+	if result == nil {
+		operationError(o.Pos, o.Operator, lhsValue, rhsValue)
+	}
+	return result
+}
+
+// operationError stops compiling when the operator could not be applied.
+func operationError(pos lexer.Position, operator string, lhsValue value.Value, rhsValue value.Value) {
+	fatalf(pos, "Cannot apply %s to %s and %s", operator, typeName(lhsValue.Type()), typeName(rhsValue.Type()))
 }
 
 // Evaluate evaluates unary
 func (u *Unary) Evaluate(scope *Scope) value.Value {
 	switch {
-	// Not is implemented yet
 	case u.Opposite != nil:
-		return OppositeEval(scope, u.Opposite.Evaluate(scope))
+		operand := u.Opposite.Evaluate(scope)
+		result := OppositeEval(scope, operand)
+		if result == nil {
+			fatalf(u.Pos, "Cannot apply - to %s", typeName(operand.Type()))
+		}
+		return result
 	case u.Primary != nil:
 		return u.Primary.Evaluate(scope)
 	}
-	log.Fatal("unreachable")
-	return nil
+	panic("unreachable")
 }
 
 // Evaluate evaluates primary
@@ -118,11 +187,17 @@ func (p *Primary) Evaluate(scope *Scope) value.Value {
 	case p.Subexpression != nil:
 		return p.Subexpression.Evaluate(scope)
 	}
-	log.Fatal("unreacheable")
-	return nil
+	panic("unreachable")
 }
 
-var stringConstantNameIndex = 0
+// Char gives the value of a CHAR constant.
+// ok is false if it is not a single byte, such as a character of several bytes in UTF-8.
+func (c *Constant) Char() (char byte, ok bool) {
+	if len(*c.VChar) != 1 {
+		return 0, false
+	}
+	return (*c.VChar)[0], true
+}
 
 // Evaluate gets the value of the constant.
 // If it is a string, it would be stored as a global variable.
@@ -130,12 +205,25 @@ func (c *Constant) Evaluate(scope *Scope) value.Value {
 	switch {
 	case c.VString != nil:
 		// Static Strings should be stored globally.
-		stringConstant := constant.NewCharArrayFromString(*c.VString + "\000")
-		stringGName := "PseudoConstant?$" + strconv.Itoa(stringConstantNameIndex)
-		stringConstantNameIndex++
+		// Both the characters and the PseudoString are constants,
+		// so the runtime never needs to allocate for literals.
+		stringData := constant.NewCharArrayFromString(*c.VString + "\000")
+		stringGName := "PseudoConstant?$" + strconv.Itoa(scope.GlobalScope.StringConstants)
+		scope.GlobalScope.StringConstants++
+		dataDef := scope.Module.NewGlobalDef(stringGName+".data", stringData)
+		stringConstant := constant.NewStruct(
+			constant.NewInt(types.I32, int64(len(*c.VString))),
+			constant.NewBitCast(dataDef, types.I8Ptr),
+		)
+		stringConstant.Typ = stringType
 		globalDef := scope.Module.NewGlobalDef(stringGName, stringConstant)
-		ptr := scope.Block.NewBitCast(globalDef, types.I8Ptr)
-		return ptr
+		return globalDef
+	case c.VChar != nil:
+		char, ok := c.Char()
+		if !ok {
+			fatalf(c.Pos, "CHAR should be a single byte: '%s'", *c.VChar)
+		}
+		return constant.NewInt(types.I8, int64(char))
 	case c.VReal != nil:
 		return constant.NewFloat(types.Double, *c.VReal)
 	case c.VInt != nil:
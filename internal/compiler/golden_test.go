@@ -0,0 +1,71 @@
+package compiler_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HankelBao/Pseudo/internal/pseudotest"
+)
+
+var update = flag.Bool("update", false, "update the .out files of TestGolden")
+
+// TestGolden compiles every program in testdata into LLVM IR,
+// and runs it with the interpreter, the bytecode VM,
+// and the executable linked with the runtime if clang is installed.
+// The output should be the same as the .out file of the program,
+// and a program reads its .in file as the input if there is one.
+// Run with -update to write the outputs of the interpreter into the .out files.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.pse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no programs in testdata")
+	}
+	hasClang := pseudotest.HasClang()
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			name := strings.TrimSuffix(file, ".pse")
+			source, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stdin, err := ioutil.ReadFile(name + ".in")
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+
+			ir := pseudotest.Compile(t, source)
+			interpreted := pseudotest.RunInterpreter(t, source, stdin).Golden()
+			if *update {
+				if err := ioutil.WriteFile(name+".out", []byte(interpreted), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(name + ".out")
+			if err != nil {
+				t.Fatalf("%s, run with -update to create it", err)
+			}
+			check := func(backend string, got string) {
+				if got != string(want) {
+					t.Errorf("output of the %s differs from %s.out\ngot:\n%s\nwant:\n%s", backend, name, got, want)
+				}
+			}
+			check("interpreter", interpreted)
+			check("VM", pseudotest.RunVM(t, source, stdin).Golden())
+			if !hasClang {
+				t.Log("clang is not installed, the compiled program is not run")
+				return
+			}
+			check("compiled program", pseudotest.RunCompiled(t, ir, stdin).Golden())
+		})
+	}
+}
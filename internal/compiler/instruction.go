@@ -1,8 +1,9 @@
 package compiler
 
 import (
-	"log"
+	"strings"
 
+	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
@@ -13,6 +14,35 @@ type Interpreter interface {
 	Compile(*Scope)
 }
 
+// Declare declares types and procedures in the main block,
+// so that they could be used before they are defined.
+func (ast *Ast) Declare(scope *Scope) {
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.TypeDefinition != nil:
+			inst.TypeDefinition.Compile(scope)
+		case inst.Procedure != nil:
+			inst.Procedure.Declare(scope)
+		case inst.Function != nil:
+			inst.Function.Declare(scope)
+		}
+	}
+}
+
+// CompileSubroutines compiles the bodies of procedures and functions in the main block.
+// This is done after the main block,
+// so that all the global variables are available.
+func (ast *Ast) CompileSubroutines(scope *Scope) {
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.Procedure != nil:
+			inst.Procedure.Compile(scope)
+		case inst.Function != nil:
+			inst.Function.Compile(scope)
+		}
+	}
+}
+
 // Compile compiles the ast
 // It splits them into different instructions.
 func (ast *Ast) Compile(scope *Scope) {
@@ -26,108 +56,153 @@ func (ast *Ast) Compile(scope *Scope) {
 			inst.Call.Compile(scope)
 		case inst.DeclareVariable != nil:
 			inst.DeclareVariable.Compile(scope)
+		case inst.TypeDefinition != nil, inst.Procedure != nil, inst.Function != nil:
+			// They have been handled by Declare and CompileSubroutines.
+			if !scope.Main {
+				fatalf(inst.Pos, "Types, procedures and functions should be defined in the main block")
+			}
+		case inst.Return != nil:
+			inst.Return.Compile(scope)
 		case inst.Assignment != nil:
 			inst.Assignment.Compile(scope)
-		case inst.PrintfD != nil:
-			inst.PrintfD.Compile(scope)
-		case inst.PrintfF != nil:
-			inst.PrintfF.Compile(scope)
 		case inst.ConditionBr != nil:
 			inst.ConditionBr.Compile(scope)
 		case inst.While != nil:
 			inst.While.Compile(scope)
 		case inst.Repeat != nil:
 			inst.Repeat.Compile(scope)
+		case inst.For != nil:
+			inst.For.Compile(scope)
+		case inst.Case != nil:
+			inst.Case.Compile(scope)
 		case inst.NullLine != nil:
 			continue
 		default:
-			log.Fatal("Unknown instruction")
+			panic("unknown instruction")
 		}
 	}
 }
 
 // Compile compiles InstOutput
+// Items are output one by one via the runtime,
+// and the line is ended after all of them.
 func (ins *InstOutput) Compile(scope *Scope) {
-	value := ins.Content.Evaluate(scope)
-	tmpPtr := scope.Block.NewBitCast(value, &types.PointerType{ElemType: &types.IntType{BitSize: 8}})
-	puts := scope.FindFunction("puts")
-	if puts == nil {
-		log.Fatal("puts not found")
+	for _, item := range ins.Items {
+		value := item.Evaluate(scope)
+		switch {
+		case value.Type() == types.I32:
+			scope.Block.NewCall(scope.FindFunction("pseudo_output_int"), value)
+		case value.Type() == types.Double:
+			scope.Block.NewCall(scope.FindFunction("pseudo_output_real"), value)
+		case value.Type() == types.I1:
+			scope.Block.NewCall(scope.FindFunction("pseudo_output_bool"), scope.Block.NewZExt(value, types.I32))
+		case value.Type() == types.I8:
+			scope.Block.NewCall(scope.FindFunction("pseudo_output_char"), scope.Block.NewZExt(value, types.I32))
+		case isString(value):
+			scope.Block.NewCall(scope.FindFunction("pseudo_output_string"), value)
+		case isDate(value):
+			scope.Block.NewCall(scope.FindFunction("pseudo_output_date"), dateCodeEval(scope, value))
+		default:
+			fatalf(ins.Pos, "Cannot OUTPUT a value of type %s", typeName(value.Type()))
+		}
 	}
-	scope.Block.NewCall(puts, tmpPtr)
+	scope.Block.NewCall(scope.FindFunction("pseudo_output_newline"))
 }
 
 func (ins *InstCall) Compile(scope *Scope) {
+	if ins.Name != nil {
+		call := &FunctionCall{Pos: ins.Pos, Name: *ins.Name}
+		call.Compile(scope)
+		return
+	}
 	ins.Function.Compile(scope)
 }
 
 // Compile compiles InstInput
+// A whole line is read and converted by the runtime,
+// which stops the program if the line is malformed.
 func (ins *InstInput) Compile(scope *Scope) {
-	// keyVar := ins.Content.Locate(scope)
+	keyPtr := ins.Content.Locate(scope)
+	keyType := keyPtr.Type().(*types.PointerType).ElemType
 
-	getchar := scope.FindFunction("getchar")
-	scope.Block.NewCall(getchar)
+	line := constant.NewInt(types.I32, int64(ins.Pos.Line))
+	inputLine := scope.FindFunction("pseudo_input_line")
+	input := scope.Block.NewCall(inputLine, line)
+
+	var result value.Value
+	switch {
+	case keyType == types.I32:
+		result = scope.Block.NewCall(scope.FindFunction("pseudo_parse_int"), input, line)
+	case keyType == types.Double:
+		result = scope.Block.NewCall(scope.FindFunction("pseudo_parse_real"), input, line)
+	case keyType == types.I1:
+		parsed := scope.Block.NewCall(scope.FindFunction("pseudo_parse_bool"), input, line)
+		result = scope.Block.NewTrunc(parsed, types.I1)
+	case keyType == types.I8:
+		parsed := scope.Block.NewCall(scope.FindFunction("pseudo_parse_char"), input, line)
+		result = scope.Block.NewTrunc(parsed, types.I8)
+	case types.Equal(keyType, stringPtrType):
+		result = input
+	case types.Equal(keyType, dateType):
+		parsed := scope.Block.NewCall(scope.FindFunction("pseudo_parse_date"), input, line)
+		result = scope.Block.NewInsertValue(constant.NewUndef(dateType), parsed, 0)
+	default:
+		fatalf(ins.Pos, "Cannot INPUT into a variable of type %s", typeName(keyType))
+	}
+	scope.Block.NewStore(result, keyPtr)
 }
 
 // Compile compiles InstDeclareVariable
 func (ins *InstDeclareVariable) Compile(scope *Scope) {
 	variableName := ins.Name
-	var variableInitial constant.Constant
-	switch {
-	case ins.Type.Int != nil:
-		variableInitial = constant.NewInt(types.I32, 0)
-		break
-	case ins.Type.REAL != nil:
-		variableInitial = constant.NewFloat(types.Double, 0.0)
-		break
-	case ins.Type.BOOL != nil:
-		variableInitial = constant.NewBool(false)
-	}
+	variableInitial := ins.Type.Initial(scope)
 
 	// If the scope in the main scope,
 	// Variables are defined globally.
+	// Otherwise, they are private variables of the scope,
+	// which are initialized each time the declaration is reached.
+	var err error
 	if scope.Main {
-		newVariable := scope.Module.NewGlobalDef(variableName, variableInitial)
-		scope.GlobalScope.RegisterVariable(variableName, newVariable)
+		newVariable := scope.Module.NewGlobalDef(symbolPrefix+variableName, variableInitial)
+		err = scope.GlobalScope.RegisterVariable(variableName, newVariable, &ins.Type)
 	} else {
-		// TODO: Private variable, allocate...
+		newVariable := scope.NewLocal(variableInitial.Type())
+		scope.Block.NewStore(variableInitial, newVariable)
+		err = scope.RegisterVariable(variableName, newVariable, &ins.Type)
+	}
+	if err != nil {
+		fatalf(ins.Pos, "%s", err)
+	}
+}
+
+// Compile compiles InstTypeDefinition
+// The record becomes a named struct of LLVM.
+func (ins *InstTypeDefinition) Compile(scope *Scope) {
+	recordType := &RecordType{Name: ins.Name, Fields: ins.Fields}
+	fieldTypes := make([]types.Type, len(ins.Fields))
+	for index, field := range ins.Fields {
+		if fieldIndex, _ := recordType.Field(field.Name); fieldIndex != index {
+			fatalf(field.Pos, "Define Field more than once in a type: %s", field.Name)
+		}
+		fieldTypes[index] = field.Type.IRType(scope)
 	}
+	recordType.IRType = types.NewStruct(fieldTypes...)
+	if err := scope.GlobalScope.RegisterType(ins.Name, recordType); err != nil {
+		fatalf(ins.Pos, "%s", err)
+	}
+	scope.Module.NewTypeDef(ins.Name, recordType.IRType)
 }
 
 // Compile compiles InstAssignment
 func (ins *InstAssignment) Compile(scope *Scope) {
 	key := ins.Left.Locate(scope)
+	keyType := key.Type().(*types.PointerType).ElemType
 	expression := ins.Right.Evaluate(scope)
-	scope.Block.NewStore(expression, key)
-}
-
-// Compile compiles InstPrintfD
-func (ins *InstPrintfD) Compile(scope *Scope) {
-	value := ins.Content.Evaluate(scope)
-
-	formatDef := scope.FindVariable("printfd_fmt")
-	if formatDef == nil {
-		log.Fatal("Cannot find printfd format")
-	}
-	formatPtr := scope.Block.NewBitCast(formatDef, types.I8Ptr)
-
-	printf := scope.FindFunction("printf")
-	scope.Block.NewCall(printf, formatPtr, value)
-}
-
-// Compile compiles InstPrintfF
-func (ins *InstPrintfF) Compile(scope *Scope) {
-	value := ins.Content.Evaluate(scope)
-
-	formatDef := scope.FindVariable("printff_fmt")
-	if formatDef == nil {
-		log.Fatal("Cannot find printff format")
+	converted := ConvertEval(scope, expression, keyType)
+	if converted == nil {
+		fatalf(ins.Pos, "Cannot assign %s to %s", typeName(expression.Type()), typeName(keyType))
 	}
-	formatPtr := scope.Block.NewBitCast(formatDef, types.I8Ptr)
-
-	printf := scope.FindFunction("printf")
-	scope.Block.NewCall(printf, formatPtr, value)
-
+	scope.Block.NewStore(converted, key)
 }
 
 // Compile compiles InstConditionBr
@@ -144,9 +219,11 @@ func (ins *InstConditionBr) Compile(scope *Scope) {
 		ins.FalseBr.Compile(falseBlockScope)
 	}
 
+	// Blocks might be split while compiling,
+	// so branch from where the scopes end up.
 	continueBlock := scope.Func.NewBlock("")
-	trueBlock.NewBr(continueBlock)
-	falseBlock.NewBr(continueBlock)
+	trueBlockScope.Block.NewBr(continueBlock)
+	falseBlockScope.Block.NewBr(continueBlock)
 
 	scope.Block.NewCondBr(condVal, trueBlock, falseBlock)
 	scope.Block = continueBlock
@@ -162,9 +239,9 @@ func (ins *InstWhile) Compile(scope *Scope) {
 
 	scope.Block.NewBr(condBlock)
 	condVal := ins.Condition.Evaluate(condBlockScope)
-	condBlock.NewCondBr(condVal, bodyBlock, continueBlock)
+	condBlockScope.Block.NewCondBr(condVal, bodyBlock, continueBlock)
 	ins.Body.Compile(bodyBlockScope)
-	bodyBlock.NewBr(condBlock)
+	bodyBlockScope.Block.NewBr(condBlock)
 
 	scope.Block = continueBlock
 }
@@ -179,19 +256,312 @@ func (ins *InstRepeat) Compile(scope *Scope) {
 	ins.Body.Compile(bodyBlockScope)
 	condVal := ins.Condition.Evaluate(bodyBlockScope)
 	// Exit repeat block when the condition is true
-	bodyBlock.NewCondBr(condVal, continueBlock, bodyBlock)
+	bodyBlockScope.Block.NewCondBr(condVal, continueBlock, bodyBlock)
+
+	scope.Block = continueBlock
+}
+
+// Compile compiles for
+// End and step are evaluated only once before the loop.
+func (ins *InstFor) Compile(scope *Scope) {
+	if ins.Next != nil && *ins.Next != ins.Counter {
+		fatalf(ins.Pos, "NEXT %s does not match FOR %s", *ins.Next, ins.Counter)
+	}
+	counter := &Variable{Pos: ins.Pos, Name: ins.Counter}
+	counterPtr, _ := counter.Locate(scope)
+	counterType := counterPtr.Type().(*types.PointerType).ElemType
+	if counterType != types.I32 && counterType != types.Double {
+		fatalf(ins.Pos, "Counter of FOR should be INT or REAL")
+	}
+	// INT bounds are allowed for REAL counters.
+	evaluate := func(expression *Expression) value.Value {
+		val := ConvertEval(scope, expression.Evaluate(scope), counterType)
+		if val == nil {
+			fatalf(ins.Pos, "Bounds of FOR should be of the same type as the counter")
+		}
+		return val
+	}
+	start := evaluate(&ins.Start)
+	end := evaluate(&ins.End)
+	var zero, step value.Value = constant.NewInt(types.I32, 0), constant.NewInt(types.I32, 1)
+	if counterType == types.Double {
+		zero, step = constant.NewFloat(types.Double, 0.0), constant.NewFloat(types.Double, 1.0)
+	}
+	if ins.Step != nil {
+		step = evaluate(ins.Step)
+	}
+	// Count up for positive steps and count down for negative steps.
+	ascending := CmpGEEval(scope, step, zero)
+	scope.Block.NewStore(start, counterPtr)
+
+	condBlock := scope.Func.NewBlock("")
+	condBlockScope := scope.NewScope(condBlock)
+	bodyBlock := scope.Func.NewBlock("")
+	bodyBlockScope := scope.NewScope(bodyBlock)
+	continueBlock := scope.Func.NewBlock("")
+
+	scope.Block.NewBr(condBlock)
+	counterVal := condBlock.NewLoad(counterPtr)
+	notAbove := CmpLEEval(condBlockScope, counterVal, end)
+	notBelow := CmpGEEval(condBlockScope, counterVal, end)
+	condVal := condBlock.NewSelect(ascending, notAbove, notBelow)
+	condBlock.NewCondBr(condVal, bodyBlock, continueBlock)
+
+	ins.Body.Compile(bodyBlockScope)
+	counterVal = bodyBlockScope.Block.NewLoad(counterPtr)
+	bodyBlockScope.Block.NewStore(AddEval(bodyBlockScope, counterVal, step), counterPtr)
+	bodyBlockScope.Block.NewBr(condBlock)
 
 	scope.Block = continueBlock
 }
 
+// Compile compiles case
+// It is compiled to a switch if all the labels are INT or CHAR constants,
+// otherwise the labels are compared one by one.
+func (ins *InstCase) Compile(scope *Scope) {
+	caseVal := ins.Value.Evaluate(scope)
+	ins.checkLabels()
+
+	continueBlock := scope.Func.NewBlock("")
+	otherwiseBlock := continueBlock
+	if ins.Otherwise != nil {
+		otherwiseBlock = scope.Func.NewBlock("")
+		otherwiseBlockScope := scope.NewScope(otherwiseBlock)
+		ins.Otherwise.Compile(otherwiseBlockScope)
+		otherwiseBlockScope.Block.NewBr(continueBlock)
+	}
+	clauseBlocks := make([]*ir.Block, len(ins.Clauses))
+	for index, clause := range ins.Clauses {
+		clauseBlocks[index] = scope.Func.NewBlock("")
+		clauseBlockScope := scope.NewScope(clauseBlocks[index])
+		clause.Body.Compile(clauseBlockScope)
+		clauseBlockScope.Block.NewBr(continueBlock)
+	}
+
+	if ins.isSwitch(caseVal) {
+		ins.compileSwitch(scope, caseVal, clauseBlocks, otherwiseBlock)
+	} else {
+		ins.compileCompares(scope, caseVal, clauseBlocks, otherwiseBlock)
+	}
+	scope.Block = continueBlock
+}
+
+// checkLabels stops compiling if a label overlaps one before it,
+// since the values matched by both could never reach the latter.
+// Both switches and compares are checked, so that it does not depend on the labels.
+func (ins *InstCase) checkLabels() {
+	labels := []*CaseLabel{}
+	for _, clause := range ins.Clauses {
+		for _, label := range clause.Labels {
+			for _, previous := range labels {
+				if label.Overlaps(previous) {
+					fatalf(label.Pos, "Label of CASE overlaps the one at line %d", previous.Pos.Line)
+				}
+			}
+			labels = append(labels, label)
+		}
+	}
+}
+
+// isSwitch checks if CASE could be compiled to a switch.
+func (ins *InstCase) isSwitch(caseVal value.Value) bool {
+	if caseVal.Type() != types.I32 && caseVal.Type() != types.I8 {
+		return false
+	}
+	for _, clause := range ins.Clauses {
+		for _, label := range clause.Labels {
+			if label.To != nil {
+				return false
+			}
+			if caseVal.Type() == types.I32 && label.From.Constant.VInt == nil {
+				return false
+			}
+			if caseVal.Type() == types.I8 && label.From.Constant.VChar == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compileSwitch jumps to the clause via a switch of LLVM.
+func (ins *InstCase) compileSwitch(scope *Scope, caseVal value.Value, clauseBlocks []*ir.Block, otherwiseBlock *ir.Block) {
+	cases := []*ir.Case{}
+	for index, clause := range ins.Clauses {
+		for _, label := range clause.Labels {
+			labelVal := label.From.Evaluate(scope).(*constant.Int)
+			cases = append(cases, ir.NewCase(labelVal, clauseBlocks[index]))
+		}
+	}
+	scope.Block.NewSwitch(caseVal, otherwiseBlock, cases...)
+}
+
+// compileCompares tests the labels one by one until one of them matches.
+func (ins *InstCase) compileCompares(scope *Scope, caseVal value.Value, clauseBlocks []*ir.Block, otherwiseBlock *ir.Block) {
+	for index, clause := range ins.Clauses {
+		var matched value.Value
+		for _, label := range clause.Labels {
+			labelMatched := label.Match(scope, caseVal)
+			if matched == nil {
+				matched = labelMatched
+			} else {
+				matched = scope.Block.NewOr(matched, labelMatched)
+			}
+		}
+		nextBlock := scope.Func.NewBlock("")
+		scope.Block.NewCondBr(matched, clauseBlocks[index], nextBlock)
+		scope.Block = nextBlock
+	}
+	scope.Block.NewBr(otherwiseBlock)
+}
+
+// Match generates IR to check if the value matches the label.
+func (label *CaseLabel) Match(scope *Scope, caseVal value.Value) value.Value {
+	from := ConvertEval(scope, label.From.Evaluate(scope), caseVal.Type())
+	if from == nil {
+		fatalf(label.Pos, "Label of CASE should be of the same type as the value")
+	}
+	if label.To == nil {
+		return CmpEQEval(scope, caseVal, from)
+	}
+	to := ConvertEval(scope, label.To.Evaluate(scope), caseVal.Type())
+	if to == nil {
+		fatalf(label.Pos, "Label of CASE should be of the same type as the value")
+	}
+	return scope.Block.NewAnd(CmpGEEval(scope, caseVal, from), CmpLEEval(scope, caseVal, to))
+}
+
+// Overlaps checks if a value could match both of the labels.
+// Labels should be of the same type, and ranges from a larger value match nothing.
+func (label *CaseLabel) Overlaps(other *CaseLabel) bool {
+	from, to := label.bounds()
+	otherFrom, otherTo := other.bounds()
+	if to.less(from) || otherTo.less(otherFrom) {
+		return false
+	}
+	return !to.less(otherFrom) && !otherTo.less(from)
+}
+
+// bounds gives the smallest and the largest values matched by the label.
+func (label *CaseLabel) bounds() (from caseBound, to caseBound) {
+	from = label.From.bound()
+	if label.To == nil {
+		return from, from
+	}
+	return from, label.To.bound()
+}
+
+// caseBound is the value of a label of CASE, which could be ordered.
+// STRINGs are kept in String, and the others are kept in Number,
+// where CHARs are their codes and BOOLs are 0 for FALSE and 1 for TRUE.
+type caseBound struct {
+	Number float64
+	String string
+}
+
+// less compares the bounds, which should be of the same type.
+func (b caseBound) less(other caseBound) bool {
+	if b.String != other.String {
+		return b.String < other.String
+	}
+	return b.Number < other.Number
+}
+
+// bound gives the value of the constant in the label without compiling it.
+func (v *CaseValue) bound() caseBound {
+	var b caseBound
+	switch c := v.Constant; {
+	case c.VString != nil:
+		b.String = *c.VString
+	case c.VChar != nil:
+		char, _ := c.Char()
+		b.Number = float64(char)
+	case c.VReal != nil:
+		b.Number = *c.VReal
+	case c.VInt != nil:
+		b.Number = float64(*c.VInt)
+	case c.VBool != nil && *c.VBool == "TRUE":
+		b.Number = 1
+	}
+	if v.Negative {
+		b.Number = -b.Number
+	}
+	return b
+}
+
+// Evaluate gets the value of the constant in the label.
+func (v *CaseValue) Evaluate(scope *Scope) value.Value {
+	val := v.Constant.Evaluate(scope)
+	if !v.Negative {
+		return val
+	}
+	switch val := val.(type) {
+	case *constant.Int:
+		if val.Type() == types.I32 {
+			return constant.NewInt(types.I32, -val.X.Int64())
+		}
+	case *constant.Float:
+		if val.Type() == types.Double {
+			x, _ := val.X.Float64()
+			return constant.NewFloat(types.Double, -x)
+		}
+	}
+	fatalf(v.Pos, "Only INT and REAL could be negative")
+	return nil
+}
+
+// cArgumentEval converts an argument of a function of C to the type of the parameter,
+// which is nil for variadic arguments.
+// STRINGs are passed as char*, and CHARs and BOOLs are promoted to int like C does.
+// nil would be returned if it could not be converted.
+func cArgumentEval(scope *Scope, val value.Value, paramType types.Type) value.Value {
+	switch {
+	case isString(val) && (paramType == nil || types.Equal(paramType, types.I8Ptr)):
+		return scope.Block.NewCall(scope.FindFunction("pseudo_string_cstr"), val)
+	case (val.Type() == types.I8 || val.Type() == types.I1) && (paramType == nil || paramType == types.I32):
+		return scope.Block.NewZExt(val, types.I32)
+	case paramType == nil:
+		if val.Type() != types.I32 && val.Type() != types.Double {
+			return nil
+		}
+		return val
+	}
+	return ConvertEval(scope, val, paramType)
+}
+
 func (f *FunctionCall) Compile(scope *Scope) value.Value {
 	fName := f.Name
+	if builtin, ok := builtinFunctions[fName]; ok {
+		fParams := make([]value.Value, len(f.Params))
+		for index, item := range f.Params {
+			fParams[index] = item.Evaluate(scope)
+		}
+		return builtin(scope, f, fParams)
+	}
+	function := scope.FindFunction(fName)
+	if function == nil {
+		fatalf(f.Pos, "Function not defined: %s", fName)
+	}
+	if params, ok := scope.FindParams(fName); ok {
+		fParams := passArguments(scope, f, params)
+		return scope.Block.NewCall(function, fParams...)
+	}
+
+	if strings.HasPrefix(fName, runtimePrefix) {
+		fatalf(f.Pos, "Function not defined: %s", fName)
+	}
 	fParams := make([]value.Value, len(f.Params))
 	for index, item := range f.Params {
-		fParams[index] = item.Evaluate(scope)
+		var paramType types.Type
+		if index < len(function.Params) {
+			paramType = function.Params[index].Type()
+		}
+		fParams[index] = cArgumentEval(scope, item.Evaluate(scope), paramType)
+		if fParams[index] == nil {
+			fatalf(item.Pos, "Argument %d of %s has a wrong type", index+1, fName)
+		}
 	}
 
-	function := scope.FindFunction(fName)
 	returnVal := scope.Block.NewCall(function, fParams...)
 	return returnVal
 }
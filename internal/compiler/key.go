@@ -1,20 +1,93 @@
 package compiler
 
 import (
-	//"github.com/llir/llvm/ir"
-	//"github.com/llir/llvm/ir/constant"
-	//"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
-	//"log"
 )
 
 // Locate tries to locate the key to a value via the scope given.
+// The rest of the variables are fields of records.
 func (key *Key) Locate(scope *Scope) value.Value {
-	return key.Variables[0].Evaluate(scope)
+	variablePtr, variableType := key.Variables[0].Locate(scope)
+	for _, field := range key.Variables[1:] {
+		variablePtr, variableType = field.LocateField(scope, variablePtr, variableType)
+	}
+	return variablePtr
 }
 
-func (v *Variable) Evaluate(scope *Scope) value.Value {
+// Locate gives the pointer to the variable and the type it points to.
+// If indices are given, the pointer is to the element of the array.
+func (v *Variable) Locate(scope *Scope) (value.Value, *VariableType) {
 	variableName := v.Name
 	variable := scope.FindVariable(variableName)
-	return variable
+	if variable == nil {
+		fatalf(v.Pos, "Variable not declared: %s", variableName)
+	}
+	return v.Index(scope, variable.Value, variable.Type)
+}
+
+// LocateField gives the pointer to the field of the record that ptr points to.
+func (v *Variable) LocateField(scope *Scope, ptr value.Value, varType *VariableType) (value.Value, *VariableType) {
+	if varType == nil || varType.CUSTOM == nil {
+		fatalf(v.Pos, "Not a record to get field %s", v.Name)
+	}
+	recordType := varType.Record(scope)
+	fieldIndex, fieldType := recordType.Field(v.Name)
+	if fieldIndex < 0 {
+		fatalf(v.Pos, "%s has no field %s", recordType.Name, v.Name)
+	}
+	zero := constant.NewInt(types.I32, 0)
+	fieldPtr := scope.Block.NewGetElementPtr(ptr, zero, constant.NewInt(types.I32, int64(fieldIndex)))
+	return v.Index(scope, fieldPtr, fieldType)
+}
+
+// Index applies the indices to the array that ptr points to.
+// Indices are offset by the lower bounds of the array.
+func (v *Variable) Index(scope *Scope, ptr value.Value, varType *VariableType) (value.Value, *VariableType) {
+	if len(v.Indices) == 0 {
+		return ptr, varType
+	}
+	if varType == nil || varType.ARRAY == nil {
+		fatalf(v.Pos, "%s is not an array", v.Name)
+	}
+	dimensions := varType.ARRAY.Dimensions
+	if len(v.Indices) != len(dimensions) {
+		fatalf(v.Pos, "%s has %d dimensions but %d indices are given", v.Name, len(dimensions), len(v.Indices))
+	}
+
+	// The first index steps through the pointer itself.
+	indices := []value.Value{constant.NewInt(types.I32, 0)}
+	for i, index := range v.Indices {
+		indexVal := index.Evaluate(scope)
+		if indexVal.Type() != types.I32 {
+			fatalf(v.Pos, "Index of array should be INT")
+		}
+		v.checkBounds(scope, indexVal, dimensions[i])
+		lower := constant.NewInt(types.I32, dimensions[i].Lower.Int())
+		indices = append(indices, scope.Block.NewSub(indexVal, lower))
+	}
+	elementPtr := scope.Block.NewGetElementPtr(ptr, indices...)
+	return elementPtr, varType.ARRAY.Element
+}
+
+// checkBounds stops the program via the runtime
+// if the index is out of the bounds of the dimension.
+// The current block of the scope continues after the check.
+func (v *Variable) checkBounds(scope *Scope, indexVal value.Value, dimension *ArrayDimension) {
+	lower := constant.NewInt(types.I32, dimension.Lower.Int())
+	upper := constant.NewInt(types.I32, dimension.Upper.Int())
+	belowLower := scope.Block.NewICmp(enum.IPredSLT, indexVal, lower)
+	aboveUpper := scope.Block.NewICmp(enum.IPredSGT, indexVal, upper)
+	outOfBounds := scope.Block.NewOr(belowLower, aboveUpper)
+
+	errorBlock := scope.Func.NewBlock("")
+	line := constant.NewInt(types.I32, int64(v.Pos.Line))
+	errorBlock.NewCall(scope.FindFunction("pseudo_index_error"), indexVal, lower, upper, line)
+	errorBlock.NewUnreachable()
+
+	continueBlock := scope.Func.NewBlock("")
+	scope.Block.NewCondBr(outOfBounds, errorBlock, continueBlock)
+	scope.Block = continueBlock
 }
@@ -1,6 +1,10 @@
 package compiler
 
 import (
+	"math"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/types"
@@ -9,6 +13,10 @@ import (
 
 // AddEval generates IR for add
 func AddEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
 	if value1.Type() == types.I32 {
 		return scope.Block.NewAdd(value1, value2)
 	} else if value1.Type() == types.Double {
@@ -19,6 +27,10 @@ func AddEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
 
 // MinusEval generates IR for minus
 func MinusEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
 	if value1.Type() == types.I32 {
 		return scope.Block.NewSub(value1, value2)
 	} else if value1.Type() == types.Double {
@@ -29,6 +41,10 @@ func MinusEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value
 
 // MultipleEval generates IR for multiple
 func MultipleEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
 	if value1.Type() == types.I32 {
 		return scope.Block.NewMul(value1, value2)
 	} else if value1.Type() == types.Double {
@@ -38,81 +54,286 @@ func MultipleEval(scope *Scope, value1 value.Value, value2 value.Value) value.Va
 }
 
 // DivideEval generates IR for divide
+// The result is always REAL, so INTs are converted first.
 func DivideEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
 	if value1.Type() == types.I32 {
-		return scope.Block.NewSDiv(value1, value2)
-	} else if value1.Type() == types.Double {
+		value1 = IntToRealEval(scope, value1)
+	}
+	if value2.Type() == types.I32 {
+		value2 = IntToRealEval(scope, value2)
+	}
+	if value1.Type() == types.Double && value2.Type() == types.Double {
 		return scope.Block.NewFDiv(value1, value2)
 	}
 	return nil
 }
 
+// IntDivideEval generates IR for DIV
+// The program stops at the line if the division would trap.
+func IntDivideEval(scope *Scope, line int, value1 value.Value, value2 value.Value) value.Value {
+	if value1.Type() == types.I32 && value2.Type() == types.I32 {
+		checkDivisor(scope, line, value1, value2)
+		return scope.Block.NewSDiv(value1, value2)
+	}
+	return nil
+}
+
+// ModEval generates IR for MOD
+// The program stops at the line if the division would trap.
+func ModEval(scope *Scope, line int, value1 value.Value, value2 value.Value) value.Value {
+	if value1.Type() == types.I32 && value2.Type() == types.I32 {
+		checkDivisor(scope, line, value1, value2)
+		return scope.Block.NewSRem(value1, value2)
+	}
+	return nil
+}
+
+// checkDivisor stops the program via the runtime
+// if the divisor is zero, or the division is INT_MIN DIV -1,
+// both of which would make sdiv and srem trap.
+// The current block of the scope continues after the check.
+func checkDivisor(scope *Scope, line int, value1 value.Value, value2 value.Value) {
+	isZero := scope.Block.NewICmp(enum.IPredEQ, value2, constant.NewInt(types.I32, 0))
+	isMin := scope.Block.NewICmp(enum.IPredEQ, value1, constant.NewInt(types.I32, math.MinInt32))
+	isMinusOne := scope.Block.NewICmp(enum.IPredEQ, value2, constant.NewInt(types.I32, -1))
+	overflows := scope.Block.NewAnd(isMin, isMinusOne)
+	traps := scope.Block.NewOr(isZero, overflows)
+
+	errorBlock := scope.Func.NewBlock("")
+	errorBlock.NewCall(scope.FindFunction("pseudo_division_error"), value2, constant.NewInt(types.I32, int64(line)))
+	errorBlock.NewUnreachable()
+
+	continueBlock := scope.Func.NewBlock("")
+	scope.Block.NewCondBr(traps, errorBlock, continueBlock)
+	scope.Block = continueBlock
+}
+
 // OppositeEval generates IR for opposite
 func OppositeEval(scope *Scope, value value.Value) value.Value {
 	if value.Type() == types.I32 {
 		return scope.Block.NewSub(constant.NewInt(types.I32, 0), value)
 	} else if value.Type() == types.Double {
-		return scope.Block.NewFSub(constant.NewFloat(types.Float, 0.0), value)
+		return scope.Block.NewFSub(constant.NewFloat(types.Double, 0.0), value)
 	}
 	return nil
 }
 
+// NotEval generates IR for NOT
+func NotEval(scope *Scope, value value.Value) value.Value {
+	return scope.Block.NewXor(value, constant.NewBool(true))
+}
+
+// IntToRealEval generates IR to convert INT to REAL
+func IntToRealEval(scope *Scope, value value.Value) value.Value {
+	return scope.Block.NewSIToFP(value, types.Double)
+}
+
+// unifyEval converts an INT to REAL if the other value is a REAL,
+// so that both values are of the same type.
+// nils would be returned if the types are incompatible.
+func unifyEval(scope *Scope, value1 value.Value, value2 value.Value) (value.Value, value.Value) {
+	if value1.Type() == types.I32 && value2.Type() == types.Double {
+		value1 = IntToRealEval(scope, value1)
+	} else if value1.Type() == types.Double && value2.Type() == types.I32 {
+		value2 = IntToRealEval(scope, value2)
+	}
+	if !types.Equal(value1.Type(), value2.Type()) {
+		return nil, nil
+	}
+	return value1, value2
+}
+
+// ConvertEval converts the value to the type when it is stored.
+// Only INT could be converted to REAL implicitly.
+// nil would be returned if the value could not be converted.
+func ConvertEval(scope *Scope, value value.Value, typ types.Type) value.Value {
+	if value.Type() == types.I32 && typ == types.Double {
+		return IntToRealEval(scope, value)
+	}
+	if !types.Equal(value.Type(), typ) {
+		return nil
+	}
+	return value
+}
+
 // CmpEQEval generates IR for =
 func CmpEQEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
-	if value1.Type() == types.I32 {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
+	if value1.Type() == types.I32 || value1.Type() == types.I8 || value1.Type() == types.I1 {
 		return scope.Block.NewICmp(enum.IPredEQ, value1, value2)
 	} else if value1.Type() == types.Double {
 		return scope.Block.NewFCmp(enum.FPredOEQ, value1, value2)
+	} else if isString(value1) {
+		result := stringCompareEval(scope, value1, value2)
+		return scope.Block.NewICmp(enum.IPredEQ, result, constant.NewInt(types.I32, 0))
+	} else if isDate(value1) {
+		return scope.Block.NewICmp(enum.IPredEQ, dateCodeEval(scope, value1), dateCodeEval(scope, value2))
 	}
 	return nil
 }
 
 // CmpNEEval generates IR for <>
 func CmpNEEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
-	if value1.Type() == types.I32 {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
+	if value1.Type() == types.I32 || value1.Type() == types.I8 || value1.Type() == types.I1 {
 		return scope.Block.NewICmp(enum.IPredNE, value1, value2)
 	} else if value1.Type() == types.Double {
 		return scope.Block.NewFCmp(enum.FPredONE, value1, value2)
+	} else if isString(value1) {
+		result := stringCompareEval(scope, value1, value2)
+		return scope.Block.NewICmp(enum.IPredNE, result, constant.NewInt(types.I32, 0))
+	} else if isDate(value1) {
+		return scope.Block.NewICmp(enum.IPredNE, dateCodeEval(scope, value1), dateCodeEval(scope, value2))
 	}
 	return nil
 }
 
 // CmpLTEval generates IR for <
 func CmpLTEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
 	if value1.Type() == types.I32 {
 		return scope.Block.NewICmp(enum.IPredSLT, value1, value2)
+	} else if value1.Type() == types.I8 {
+		// Characters are ordered by their unsigned codes.
+		return scope.Block.NewICmp(enum.IPredULT, value1, value2)
 	} else if value1.Type() == types.Double {
 		return scope.Block.NewFCmp(enum.FPredOLT, value1, value2)
+	} else if isString(value1) {
+		result := stringCompareEval(scope, value1, value2)
+		return scope.Block.NewICmp(enum.IPredSLT, result, constant.NewInt(types.I32, 0))
+	} else if isDate(value1) {
+		return scope.Block.NewICmp(enum.IPredSLT, dateCodeEval(scope, value1), dateCodeEval(scope, value2))
 	}
 	return nil
 }
 
 // CmpLEEval generates IR for <=
 func CmpLEEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
 	if value1.Type() == types.I32 {
 		return scope.Block.NewICmp(enum.IPredSLE, value1, value2)
+	} else if value1.Type() == types.I8 {
+		// Characters are ordered by their unsigned codes.
+		return scope.Block.NewICmp(enum.IPredULE, value1, value2)
 	} else if value1.Type() == types.Double {
 		return scope.Block.NewFCmp(enum.FPredOLE, value1, value2)
+	} else if isString(value1) {
+		result := stringCompareEval(scope, value1, value2)
+		return scope.Block.NewICmp(enum.IPredSLE, result, constant.NewInt(types.I32, 0))
+	} else if isDate(value1) {
+		return scope.Block.NewICmp(enum.IPredSLE, dateCodeEval(scope, value1), dateCodeEval(scope, value2))
 	}
 	return nil
 }
 
 // CmpGTEval generates IR for >
 func CmpGTEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
 	if value1.Type() == types.I32 {
 		return scope.Block.NewICmp(enum.IPredSGT, value1, value2)
+	} else if value1.Type() == types.I8 {
+		// Characters are ordered by their unsigned codes.
+		return scope.Block.NewICmp(enum.IPredUGT, value1, value2)
 	} else if value1.Type() == types.Double {
 		return scope.Block.NewFCmp(enum.FPredOGT, value1, value2)
+	} else if isString(value1) {
+		result := stringCompareEval(scope, value1, value2)
+		return scope.Block.NewICmp(enum.IPredSGT, result, constant.NewInt(types.I32, 0))
+	} else if isDate(value1) {
+		return scope.Block.NewICmp(enum.IPredSGT, dateCodeEval(scope, value1), dateCodeEval(scope, value2))
 	}
 	return nil
 }
 
 // CmpGEEval generates IR for >=
 func CmpGEEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	value1, value2 = unifyEval(scope, value1, value2)
+	if value1 == nil {
+		return nil
+	}
 	if value1.Type() == types.I32 {
 		return scope.Block.NewICmp(enum.IPredSGE, value1, value2)
+	} else if value1.Type() == types.I8 {
+		// Characters are ordered by their unsigned codes.
+		return scope.Block.NewICmp(enum.IPredUGE, value1, value2)
 	} else if value1.Type() == types.Double {
 		return scope.Block.NewFCmp(enum.FPredOGE, value1, value2)
+	} else if isString(value1) {
+		result := stringCompareEval(scope, value1, value2)
+		return scope.Block.NewICmp(enum.IPredSGE, result, constant.NewInt(types.I32, 0))
+	} else if isDate(value1) {
+		return scope.Block.NewICmp(enum.IPredSGE, dateCodeEval(scope, value1), dateCodeEval(scope, value2))
 	}
 	return nil
 }
+
+// isString checks if the value is a STRING.
+func isString(val value.Value) bool {
+	return types.Equal(val.Type(), stringPtrType)
+}
+
+// stringCompareEval compares two STRINGs via the runtime.
+// The result is negative, zero or positive like strcmp.
+func stringCompareEval(scope *Scope, value1 value.Value, value2 value.Value) value.Value {
+	compare := scope.FindFunction("pseudo_string_compare")
+	return scope.Block.NewCall(compare, value1, value2)
+}
+
+// isDate checks if the value is a DATE.
+func isDate(val value.Value) bool {
+	return types.Equal(val.Type(), dateType)
+}
+
+// dateCodeEval gives the YYYYMMDD code of a DATE,
+// which keeps the order of dates.
+func dateCodeEval(scope *Scope, val value.Value) value.Value {
+	return scope.Block.NewExtractValue(val, 0)
+}
+
+// shortCircuitEval generates IR for AND and OR.
+// rhs is evaluated in a new block,
+// which is skipped if the result is known from lhs.
+func shortCircuitEval(scope *Scope, pos lexer.Position, operator string, lhsValue value.Value, rhs func(*Scope) value.Value) value.Value {
+	if lhsValue.Type() != types.I1 {
+		fatalf(pos, "%s takes BOOLs", operator)
+	}
+	lhsBlock := scope.Block
+	rhsBlock := scope.Func.NewBlock("")
+	continueBlock := scope.Func.NewBlock("")
+
+	// The result is lhs itself if rhs is skipped.
+	var skipped constant.Constant
+	if operator == "OR" {
+		lhsBlock.NewCondBr(lhsValue, continueBlock, rhsBlock)
+		skipped = constant.NewBool(true)
+	} else {
+		lhsBlock.NewCondBr(lhsValue, rhsBlock, continueBlock)
+		skipped = constant.NewBool(false)
+	}
+
+	scope.Block = rhsBlock
+	rhsValue := rhs(scope)
+	if rhsValue.Type() != types.I1 {
+		fatalf(pos, "%s takes BOOLs", operator)
+	}
+	scope.Block.NewBr(continueBlock)
+
+	result := continueBlock.NewPhi(ir.NewIncoming(skipped, lhsBlock), ir.NewIncoming(rhsValue, scope.Block))
+	scope.Block = continueBlock
+	return result
+}
@@ -2,7 +2,7 @@ package compiler
 
 import (
 	"io"
-	"log"
+	"unicode/utf8"
 
 	"github.com/alecthomas/participle"
 	"github.com/alecthomas/participle/lexer"
@@ -10,10 +10,12 @@ import (
 )
 
 // Parse parses the input into ast.
-func Parse(f io.Reader) *Ast {
+// Syntax errors are given as Diagnostics.
+func Parse(f io.Reader) (*Ast, error) {
 	pseLexer := lexer.Must(ebnf.New(`
 		Ident = (alpha | "_") { "_" | alpha | digit } .
 		String = "\"" { "\u0000"…"\uffff"-"\""-"\\" | "\\" any } "\"" .
+		Char = "'" ( "\u0000"…"\uffff"-"'"-"\\" | "\\" any ) "'" .
 		Float = digit {digit} "." digit {digit} .
 		Int = digit {digit} .
 		EOL = [ "\r" ] "\n" .
@@ -26,19 +28,74 @@ func Parse(f io.Reader) *Ast {
 	`))
 
 	parser, parserErr := participle.Build(&Ast{},
-		participle.Lexer(pseLexer),
-		participle.Unquote("String"),
+		participle.Lexer(positionDefinition{pseLexer}),
+		participle.Unquote("String", "Char"),
 		//participle.UseLookahead(0),
 		participle.Elide("Whitespace"),
 	)
 	if parserErr != nil {
-		log.Fatal(parserErr)
+		return nil, parserErr
 	}
 
 	ast := &Ast{}
 	parseErr := parser.Parse(f, ast)
+	if lexerErr, ok := parseErr.(*lexer.Error); ok {
+		return nil, newDiagnostic(lexerErr.Pos, codeSyntax, lexerErr.Message)
+	}
 	if parseErr != nil {
-		log.Fatal(parseErr)
+		return nil, parseErr
+	}
+	return ast, nil
+}
+
+// positionDefinition fixes the positions of the tokens given by the ebnf lexer,
+// which keep moving forward while productions which do not match are tried.
+type positionDefinition struct {
+	lexer.Definition
+}
+
+// Lex lexes the input with the ebnf lexer,
+// and positions are counted from the values of the tokens instead.
+func (d positionDefinition) Lex(r io.Reader) (lexer.Lexer, error) {
+	l, err := d.Definition.Lex(r)
+	if err != nil {
+		return nil, err
 	}
-	return ast
+	return &positionLexer{Lexer: l, pos: lexer.Position{Line: 1, Column: 1}}, nil
+}
+
+// positionLexer counts the positions of tokens, including whitespaces,
+// which are elided by the parser later.
+type positionLexer struct {
+	lexer.Lexer
+	pos lexer.Position
+}
+
+// Next gives the next token at the position after the previous one.
+func (l *positionLexer) Next() (lexer.Token, error) {
+	token, err := l.Lexer.Next()
+	if lexerErr, ok := err.(*lexer.Error); ok {
+		lexerErr.Pos = l.at(lexerErr.Pos)
+	}
+	if err != nil {
+		return token, err
+	}
+	token.Pos = l.at(token.Pos)
+	for _, rn := range token.Value {
+		l.pos.Offset += utf8.RuneLen(rn)
+		if rn == '\n' {
+			l.pos.Line++
+			l.pos.Column = 1
+		} else {
+			l.pos.Column++
+		}
+	}
+	return token, nil
+}
+
+// at gives the counted position with the filename of the given one.
+func (l *positionLexer) at(pos lexer.Position) lexer.Position {
+	counted := l.pos
+	counted.Filename = pos.Filename
+	return counted
 }
@@ -0,0 +1,303 @@
+//go:build ignore
+// +build ignore
+
+// The runtime is embedded into the compiler as RuntimeSource and linked by clang,
+// so it is excluded from the Go build by the constraint above.
+
+#include <ctype.h>
+#include <limits.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+
+int scanf(const char *format, ...);
+int getchar();
+int putchar(int);
+
+// PseudoString is the representation of STRING.
+// Strings are immutable once created, so they can be shared freely.
+// A NULL pointer stands for an empty string.
+typedef struct PseudoString {
+    int length;
+    char *data;
+} PseudoString;
+
+// pseudo_string_new creates a string which takes the ownership of data.
+// data should be null-terminated.
+PseudoString *pseudo_string_new(char *data, int length) {
+    PseudoString *s = malloc(sizeof(PseudoString));
+    s->length = length;
+    s->data = data;
+    return s;
+}
+
+// pseudo_string_cstr gives a null-terminated view of the string for C functions.
+const char *pseudo_string_cstr(PseudoString *s) {
+    if (s == NULL) {
+        return "";
+    }
+    return s->data;
+}
+
+// pseudo_string_compare compares two strings like strcmp.
+int pseudo_string_compare(PseudoString *a, PseudoString *b) {
+    int lengthA = a == NULL ? 0 : a->length;
+    int lengthB = b == NULL ? 0 : b->length;
+    int length = lengthA < lengthB ? lengthA : lengthB;
+    int result = length == 0 ? 0 : memcmp(a->data, b->data, length);
+    if (result != 0) {
+        return result;
+    }
+    return lengthA - lengthB;
+}
+
+// pseudo_output_string outputs a STRING.
+void pseudo_output_string(PseudoString *s) {
+    if (s != NULL) {
+        fwrite(s->data, 1, s->length, stdout);
+    }
+}
+
+// pseudo_output_char outputs a CHAR.
+void pseudo_output_char(int c) {
+    putchar(c);
+}
+
+// pseudo_output_int outputs an INT.
+void pseudo_output_int(int i) {
+    printf("%d", i);
+}
+
+// pseudo_output_real outputs a REAL.
+// Whole numbers keep a decimal point, such as 3.0,
+// so that they could be told from INTs.
+void pseudo_output_real(double r) {
+    char buffer[32];
+    snprintf(buffer, sizeof(buffer), "%.15g", r);
+    if (strpbrk(buffer, ".eni") == NULL) {
+        strcat(buffer, ".0");
+    }
+    fputs(buffer, stdout);
+}
+
+// pseudo_output_bool outputs a BOOL as TRUE or FALSE.
+void pseudo_output_bool(int b) {
+    fputs(b ? "TRUE" : "FALSE", stdout);
+}
+
+// pseudo_output_date outputs a DATE in the format of DD/MM/YYYY.
+void pseudo_output_date(int date) {
+    printf("%02d/%02d/%04d", date % 100, date / 100 % 100, date / 10000);
+}
+
+// pseudo_output_newline ends the line of OUTPUT.
+void pseudo_output_newline(void) {
+    putchar('\n');
+}
+
+// pseudo_rand gives a random REAL in [0, max).
+// The generator is seeded the first time it is used.
+double pseudo_rand(int max) {
+    static int seeded = 0;
+    if (!seeded) {
+        srand((unsigned)time(NULL));
+        seeded = 1;
+    }
+    return rand() / (RAND_MAX + 1.0) * max;
+}
+
+// pseudo_round rounds a REAL to the number of decimal places.
+// Halves are rounded away from zero.
+// Negative places round to tens, hundreds and so on.
+double pseudo_round(double r, int places) {
+    double scale = 1.0;
+    for (int i = 0; i < places; i++) {
+        scale *= 10.0;
+    }
+    for (int i = 0; i > places; i--) {
+        scale /= 10.0;
+    }
+    double scaled = r * scale;
+    // Numbers this large have no fraction part to round.
+    if (scaled >= 9e18 || scaled <= -9e18 || scaled != scaled) {
+        return r;
+    }
+    long long rounded = (long long)(scaled < 0 ? scaled - 0.5 : scaled + 0.5);
+    return rounded / scale;
+}
+
+// pseudo_index_error stops the program when an index is out of the bounds of an array.
+void pseudo_index_error(int index, int lower, int upper, int line) {
+    fflush(stdout);
+    fprintf(stderr, "Index %d out of bounds [%d:%d] at line %d\n", index, lower, upper, line);
+    exit(1);
+}
+
+// pseudo_division_error stops the program when DIV or MOD would trap.
+// The divisor is either zero, or -1 while the dividend is INT_MIN.
+void pseudo_division_error(int divisor, int line) {
+    fflush(stdout);
+    if (divisor == 0) {
+        fprintf(stderr, "Division by zero at line %d\n", line);
+    } else {
+        fprintf(stderr, "Integer overflow at line %d\n", line);
+    }
+    exit(1);
+}
+
+// pseudo_input_line reads a line from stdin without the line break.
+PseudoString *pseudo_input_line(int line) {
+    int capacity = 64;
+    int length = 0;
+    char *data = malloc(capacity);
+    int c;
+    while ((c = getchar()) != EOF && c != '\n') {
+        if (length + 1 >= capacity) {
+            capacity *= 2;
+            data = realloc(data, capacity);
+        }
+        data[length++] = c;
+    }
+    if (c == EOF && length == 0) {
+        fflush(stdout);
+        fprintf(stderr, "No more input at line %d\n", line);
+        exit(1);
+    }
+    if (length > 0 && data[length - 1] == '\r') {
+        length--;
+    }
+    data[length] = '\0';
+    return pseudo_string_new(data, length);
+}
+
+// pseudo_input_error stops the program when the input could not be converted.
+static void pseudo_input_error(const char *type, PseudoString *s, int line) {
+    fflush(stdout);
+    fprintf(stderr, "Invalid %s input '%s' at line %d\n", type, pseudo_string_cstr(s), line);
+    exit(1);
+}
+
+// pseudo_trim gives the bounds of the string without the spaces around.
+static void pseudo_trim(PseudoString *s, const char **begin, const char **end) {
+    *begin = s->data;
+    *end = s->data + s->length;
+    while (*begin < *end && isspace((unsigned char)**begin)) {
+        (*begin)++;
+    }
+    while (*end > *begin && isspace((unsigned char)*(*end - 1))) {
+        (*end)--;
+    }
+}
+
+// pseudo_scan_digits skips the digits and gives the number of them.
+static int pseudo_scan_digits(const char **p, const char *end) {
+    int count = 0;
+    while (*p < end && isdigit((unsigned char)**p)) {
+        (*p)++;
+        count++;
+    }
+    return count;
+}
+
+// pseudo_parse_int converts the input to INT.
+// It should be digits with an optional sign.
+int pseudo_parse_int(PseudoString *s, int line) {
+    const char *begin, *end;
+    pseudo_trim(s, &begin, &end);
+    const char *p = begin;
+    if (p < end && (*p == '+' || *p == '-')) {
+        p++;
+    }
+    if (pseudo_scan_digits(&p, end) == 0 || p != end) {
+        pseudo_input_error("INT", s, line);
+    }
+    long long value = strtoll(begin, NULL, 10);
+    if (value < INT_MIN || value > INT_MAX || end - begin > 12) {
+        pseudo_input_error("INT", s, line);
+    }
+    return (int)value;
+}
+
+// pseudo_parse_real converts the input to REAL.
+// It should be a decimal number with an optional exponent.
+double pseudo_parse_real(PseudoString *s, int line) {
+    const char *begin, *end;
+    pseudo_trim(s, &begin, &end);
+    const char *p = begin;
+    if (p < end && (*p == '+' || *p == '-')) {
+        p++;
+    }
+    int digits = pseudo_scan_digits(&p, end);
+    if (p < end && *p == '.') {
+        p++;
+        digits += pseudo_scan_digits(&p, end);
+    }
+    if (digits > 0 && p < end && (*p == 'e' || *p == 'E')) {
+        p++;
+        if (p < end && (*p == '+' || *p == '-')) {
+            p++;
+        }
+        if (pseudo_scan_digits(&p, end) == 0) {
+            digits = 0;
+        }
+    }
+    if (digits == 0 || p != end) {
+        pseudo_input_error("REAL", s, line);
+    }
+    return strtod(begin, NULL);
+}
+
+// pseudo_parse_bool converts the input to BOOL.
+// It should be TRUE or FALSE in any case.
+int pseudo_parse_bool(PseudoString *s, int line) {
+    const char *begin, *end;
+    pseudo_trim(s, &begin, &end);
+    if (end - begin == 4 && strncasecmp(begin, "TRUE", 4) == 0) {
+        return 1;
+    }
+    if (end - begin == 5 && strncasecmp(begin, "FALSE", 5) == 0) {
+        return 0;
+    }
+    pseudo_input_error("BOOL", s, line);
+    return 0;
+}
+
+// pseudo_parse_char converts the input to CHAR.
+// It should be exactly one character.
+int pseudo_parse_char(PseudoString *s, int line) {
+    if (s->length != 1) {
+        pseudo_input_error("CHAR", s, line);
+    }
+    return (unsigned char)s->data[0];
+}
+
+// pseudo_parse_date converts the input to DATE.
+// It should be in the format of DD/MM/YYYY,
+// and it is stored as YYYYMMDD so that dates could be compared as integers.
+int pseudo_parse_date(PseudoString *s, int line) {
+    static const int days[] = {31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31};
+    const char *begin, *end;
+    pseudo_trim(s, &begin, &end);
+    const char *p = begin;
+    int dayDigits = pseudo_scan_digits(&p, end);
+    int monthDigits = 0, yearDigits = 0;
+    if (p < end && *p == '/') {
+        p++;
+        monthDigits = pseudo_scan_digits(&p, end);
+        if (p < end && *p == '/') {
+            p++;
+            yearDigits = pseudo_scan_digits(&p, end);
+        }
+    }
+    if (dayDigits < 1 || dayDigits > 2 || monthDigits < 1 || monthDigits > 2 || yearDigits != 4 || p != end) {
+        pseudo_input_error("DATE", s, line);
+    }
+    int day = 0, month = 0, year = 0;
+    sscanf(begin, "%d/%d/%d", &day, &month, &year);
+    int leap = (year % 4 == 0 && year % 100 != 0) || year % 400 == 0;
+    if (month < 1 || month > 12 || day < 1 || day > days[month - 1] || (month == 2 && day == 29 && !leap)) {
+        pseudo_input_error("DATE", s, line);
+    }
+    return year * 10000 + month * 100 + day;
+}
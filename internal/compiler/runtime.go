@@ -1,20 +1,61 @@
 package compiler
 
 import (
-	"log"
+	// embed is imported for RuntimeSource.
+	_ "embed"
+	"strings"
 
 	"github.com/llir/llvm/ir"
-	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
 )
 
+// RuntimeSource is the C source of the runtime,
+// which is linked with the compiled module into executables.
+//
+//go:embed runtime.c
+var RuntimeSource string
+
+// stringType is the representation of STRING.
+// It must be kept the same as PseudoString in runtime.c.
+var stringType = &types.StructType{
+	TypeName: "PseudoString",
+	Fields:   []types.Type{types.I32, types.I8Ptr},
+}
+
+// stringPtrType is the type of STRING values.
+// Strings are always passed around by pointer.
+var stringPtrType = types.NewPointer(stringType)
+
+// dateType is the representation of DATE.
+// The date is stored as YYYYMMDD so that it could be compared as an INT,
+// while it is wrapped in a struct to be distinguished from INT.
+var dateType = &types.StructType{
+	TypeName: "PseudoDate",
+	Fields:   []types.Type{types.I32},
+}
+
+// runtimePrefix starts the names of the functions in runtime.c,
+// which are only used by the compiler and could not be called in pseudocode.
+const runtimePrefix = "pseudo_"
+
+// FindCFunction locates a function of the C standard library which could be called in pseudocode,
+// such as puts and putchar. Functions of runtime.c are never found.
+func (scope *Scope) FindCFunction(name string) *ir.Func {
+	if strings.HasPrefix(name, runtimePrefix) {
+		return nil
+	}
+	return scope.FindFunction(name)
+}
+
 // InitRuntime inits the runtime for pseudocode.
 // This includes:
 // - C Standard Functions
-// - Format for PrintfD and PrintfF
+// - STRING functions in runtime.c
+// - INPUT and OUTPUT functions in runtime.c
+// - Numeric built-in functions in runtime.c
 func (scope *Scope) InitRuntime() {
 	if scope.IsGlobal() == false {
-		log.Fatal("Init C Shared Lib to non-global scope")
+		panic("InitRuntime should be called on the global scope")
 	}
 	mod := scope.Module
 
@@ -35,11 +76,66 @@ func (scope *Scope) InitRuntime() {
 	putchar := mod.NewFunc("putchar", types.I32, ir.NewParam("", types.I32))
 	scope.RegisterFunction("putchar", putchar)
 
-	printfdFmt := constant.NewCharArrayFromString("Int: %d\n\000")
-	printfdFmtDef := mod.NewGlobalDef("printfd_fmt", printfdFmt)
-	scope.RegisterVariable("printfd_fmt", printfdFmtDef)
+	mod.TypeDefs = append(mod.TypeDefs, stringType, dateType)
+
+	stringCStr := mod.NewFunc("pseudo_string_cstr", types.I8Ptr, ir.NewParam("", stringPtrType))
+	scope.RegisterFunction("pseudo_string_cstr", stringCStr)
+
+	stringCompare := mod.NewFunc("pseudo_string_compare", types.I32, ir.NewParam("", stringPtrType), ir.NewParam("", stringPtrType))
+	scope.RegisterFunction("pseudo_string_compare", stringCompare)
+
+	outputString := mod.NewFunc("pseudo_output_string", types.Void, ir.NewParam("", stringPtrType))
+	scope.RegisterFunction("pseudo_output_string", outputString)
+
+	outputChar := mod.NewFunc("pseudo_output_char", types.Void, ir.NewParam("", types.I32))
+	scope.RegisterFunction("pseudo_output_char", outputChar)
+
+	outputInt := mod.NewFunc("pseudo_output_int", types.Void, ir.NewParam("", types.I32))
+	scope.RegisterFunction("pseudo_output_int", outputInt)
+
+	outputReal := mod.NewFunc("pseudo_output_real", types.Void, ir.NewParam("", types.Double))
+	scope.RegisterFunction("pseudo_output_real", outputReal)
+
+	outputBool := mod.NewFunc("pseudo_output_bool", types.Void, ir.NewParam("", types.I32))
+	scope.RegisterFunction("pseudo_output_bool", outputBool)
+
+	outputDate := mod.NewFunc("pseudo_output_date", types.Void, ir.NewParam("", types.I32))
+	scope.RegisterFunction("pseudo_output_date", outputDate)
+
+	outputNewline := mod.NewFunc("pseudo_output_newline", types.Void)
+	scope.RegisterFunction("pseudo_output_newline", outputNewline)
+
+	random := mod.NewFunc("pseudo_rand", types.Double, ir.NewParam("", types.I32))
+	scope.RegisterFunction("pseudo_rand", random)
+
+	round := mod.NewFunc("pseudo_round", types.Double, ir.NewParam("", types.Double), ir.NewParam("places", types.I32))
+	scope.RegisterFunction("pseudo_round", round)
+
+	indexError := mod.NewFunc("pseudo_index_error", types.Void,
+		ir.NewParam("index", types.I32), ir.NewParam("lower", types.I32),
+		ir.NewParam("upper", types.I32), ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_index_error", indexError)
+
+	divisionError := mod.NewFunc("pseudo_division_error", types.Void,
+		ir.NewParam("divisor", types.I32), ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_division_error", divisionError)
+
+	inputLine := mod.NewFunc("pseudo_input_line", stringPtrType, ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_input_line", inputLine)
+
+	parseInt := mod.NewFunc("pseudo_parse_int", types.I32, ir.NewParam("", stringPtrType), ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_parse_int", parseInt)
+
+	parseReal := mod.NewFunc("pseudo_parse_real", types.Double, ir.NewParam("", stringPtrType), ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_parse_real", parseReal)
+
+	parseBool := mod.NewFunc("pseudo_parse_bool", types.I32, ir.NewParam("", stringPtrType), ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_parse_bool", parseBool)
+
+	parseChar := mod.NewFunc("pseudo_parse_char", types.I32, ir.NewParam("", stringPtrType), ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_parse_char", parseChar)
+
+	parseDate := mod.NewFunc("pseudo_parse_date", types.I32, ir.NewParam("", stringPtrType), ir.NewParam("line", types.I32))
+	scope.RegisterFunction("pseudo_parse_date", parseDate)
 
-	printffFmt := constant.NewCharArrayFromString("Int: %f\n\000")
-	printffFmtDef := mod.NewGlobalDef("printff_fmt", printffFmt)
-	scope.RegisterVariable("printff_fmt", printffFmtDef)
 }
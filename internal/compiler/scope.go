@@ -1,21 +1,37 @@
 package compiler
 
 import (
-	"log"
+	"fmt"
 
 	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 )
 
 
-// ScopeVariableMap is a map to store all the variables in a scope.
+// ScopeVariable is a variable registered in a scope.
 // value.Value is an interface,
 // so it should not be a ptr here.
-type ScopeVariableMap map[string]value.Value
+type ScopeVariable struct {
+	Value value.Value
+	// Type is the declared type, which is needed for indexing arrays.
+	// It is nil for variables defined by the runtime.
+	Type *VariableType
+}
+
+// ScopeVariableMap is a map to store all the variables in a scope.
+type ScopeVariableMap map[string]*ScopeVariable
 // ScopeFuncMap is a map to store all the functions in a scope.
 // ir.Func is a struct
 type ScopeFuncMap map[string]*ir.Func
 
+// ScopeParamMap is a map to store the parameters of procedures and functions.
+// It tells how arguments should be passed.
+type ScopeParamMap map[string][]*Parameter
+
+// ScopeTypeMap is a map to store all the record types in a scope.
+type ScopeTypeMap map[string]*RecordType
+
 // Scope keep track of all the informations in a block/sub-block.
 type Scope struct {
 	Module *ir.Module
@@ -24,12 +40,18 @@ type Scope struct {
 
 	Variables ScopeVariableMap
 	Functions ScopeFuncMap
+	Params    ScopeParamMap
+	Types     ScopeTypeMap
 
 	// For Pseudocode, Anything in the root level belongs to function main.
 	// So variables defined in main block are global variables,
 	// variables defined in other blocks are private variables.
 	Main bool
 
+	// StringConstants counts the string constants of the module,
+	// which are named by the count. It is only used in GlobalScope.
+	StringConstants int
+
 	// When the current scope is the same as GlobalScope,
 	// it is the root scope.
 	// Keep this field for function access and constant definition.
@@ -46,6 +68,8 @@ func NewGlobalScope() *Scope {
 		Block:       nil,
 		Variables:   make(ScopeVariableMap),
 		Functions:   make(ScopeFuncMap),
+		Params:      make(ScopeParamMap),
+		Types:       make(ScopeTypeMap),
 		Main:        false,
 		GlobalScope: nil,
 		Parent:      nil,
@@ -62,6 +86,8 @@ func (scope *Scope) NewFuncScope(function *ir.Func) *Scope {
 		Block:       function.NewBlock(""),
 		Variables:   make(ScopeVariableMap),
 		Functions:   nil,
+		Params:      nil,
+		Types:       nil,
 		Main:        false,
 		GlobalScope: scope.GlobalScope,
 		Parent:      scope,
@@ -72,7 +98,7 @@ func (scope *Scope) NewFuncScope(function *ir.Func) *Scope {
 // NewScope creates a new scope under the given scope.
 func (scope *Scope) NewScope(block *ir.Block) *Scope {
 	if scope.Func == nil {
-		log.Fatal("Cannot new a scope here!")
+		panic("NewScope should be called in a function")
 	}
 	newScope := Scope{
 		Module:      scope.Module,
@@ -80,6 +106,8 @@ func (scope *Scope) NewScope(block *ir.Block) *Scope {
 		Block:       block,
 		Variables:   make(ScopeVariableMap),
 		Functions:   nil,
+		Params:      nil,
+		Types:       nil,
 		Main:        false,
 		GlobalScope: scope.GlobalScope,
 		Parent:      scope,
@@ -88,17 +116,30 @@ func (scope *Scope) NewScope(block *ir.Block) *Scope {
 }
 
 // RegisterVariable register a variable to the current scope for further usages.
-func (scope *Scope) RegisterVariable(name string, val value.Value) {
+// Variables in parent scopes could be shadowed,
+// but a variable could only be defined once in a scope.
+func (scope *Scope) RegisterVariable(name string, val value.Value, varType *VariableType) error {
 	_, ok := scope.Variables[name]
 	if ok {
-		log.Fatal("Define Variable more than once in a scope: ", name)
+		return fmt.Errorf("Define Variable more than once in a scope: %s", name)
 	}
-	scope.Variables[name] = val
+	scope.Variables[name] = &ScopeVariable{Value: val, Type: varType}
+	return nil
+}
+
+// NewLocal allocates a private variable on the stack of the function.
+// Allocas are put at the start of the entry block,
+// so that variables declared in loops would not grow the stack.
+func (scope *Scope) NewLocal(typ types.Type) *ir.InstAlloca {
+	entryBlock := scope.Func.Blocks[0]
+	alloca := ir.NewAlloca(typ)
+	entryBlock.Insts = append([]ir.Instruction{alloca}, entryBlock.Insts...)
+	return alloca
 }
 
 // FindVariable locates the variable registered.
 // If the variable is not found, nil would be returned.
-func (scope *Scope) FindVariable(name string) value.Value {
+func (scope *Scope) FindVariable(name string) *ScopeVariable {
 	currentScope := scope
 	for {
 		val, ok := currentScope.Variables[name]
@@ -114,12 +155,13 @@ func (scope *Scope) FindVariable(name string) value.Value {
 
 // RegisterFunction registers a function to the current scope for further usages.
 // Functions should be registered to global scope only!
-func (scope *Scope) RegisterFunction(name string, value *ir.Func) {
+func (scope *Scope) RegisterFunction(name string, value *ir.Func) error {
 	_, ok := scope.Functions[name]
 	if ok {
-		log.Fatal("Define Function more than once in a scope: ", name)
+		return fmt.Errorf("Define Function more than once: %s", name)
 	}
 	scope.Functions[name] = value
+	return nil
 }
 
 // FindFunction locates the function in the current scope.
@@ -133,6 +175,42 @@ func (scope *Scope) FindFunction(name string) *ir.Func {
 	return nil
 }
 
+// RegisterParams registers the parameters of a procedure or function defined in pseudocode.
+// Functions of the runtime have no parameters registered.
+func (scope *Scope) RegisterParams(name string, params []*Parameter) {
+	scope.Params[name] = params
+}
+
+// FindParams locates the parameters of a procedure or function.
+// If it is not defined in pseudocode, ok would be false.
+func (scope *Scope) FindParams(name string) (params []*Parameter, ok bool) {
+	// Parameters only restored in globalscope.
+	params, ok = scope.GlobalScope.Params[name]
+	return
+}
+
+// RegisterType registers a record type to the current scope for further usages.
+// Types should be registered to global scope only!
+func (scope *Scope) RegisterType(name string, recordType *RecordType) error {
+	_, ok := scope.Types[name]
+	if ok {
+		return fmt.Errorf("Define Type more than once: %s", name)
+	}
+	scope.Types[name] = recordType
+	return nil
+}
+
+// FindType locates the record type in the current scope.
+func (scope *Scope) FindType(name string) *RecordType {
+	// Types only restored in globalscope.
+	currentScope := scope.GlobalScope
+	val, ok := currentScope.Types[name]
+	if ok {
+		return val
+	}
+	return nil
+}
+
 // IsGlobal checks if the current scope is the global scope.
 func (scope *Scope) IsGlobal() bool {
 	if scope == scope.GlobalScope {
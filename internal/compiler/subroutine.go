@@ -0,0 +1,168 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// IRType gives the LLVM type of the parameter.
+// Parameters passed BYREF are pointers.
+func (p *Parameter) IRType(scope *Scope) types.Type {
+	paramType := p.Type.IRType(scope)
+	if p.BYREF {
+		return types.NewPointer(paramType)
+	}
+	return paramType
+}
+
+// Declare creates the function of the procedure and registers it.
+// The body is compiled later.
+func (ins *InstProcedure) Declare(scope *Scope) {
+	function := scope.Module.NewFunc(symbolPrefix+ins.Name, types.Void, newIRParams(scope, ins.Params)...)
+	if err := scope.GlobalScope.RegisterFunction(ins.Name, function); err != nil {
+		fatalf(ins.Pos, "%s", err)
+	}
+	scope.GlobalScope.RegisterParams(ins.Name, ins.Params)
+}
+
+// Compile compiles the body of the procedure.
+func (ins *InstProcedure) Compile(scope *Scope) {
+	function := scope.FindFunction(ins.Name)
+	funcScope := scope.NewFuncScope(function)
+	registerParams(funcScope, ins.Params)
+	ins.Body.Compile(funcScope)
+	funcScope.Block.NewRet(nil)
+}
+
+// Declare creates the function and registers it.
+// The body is compiled later.
+func (ins *InstFunction) Declare(scope *Scope) {
+	returnType := ins.ReturnType.IRType(scope)
+	function := scope.Module.NewFunc(symbolPrefix+ins.Name, returnType, newIRParams(scope, ins.Params)...)
+	if err := scope.GlobalScope.RegisterFunction(ins.Name, function); err != nil {
+		fatalf(ins.Pos, "%s", err)
+	}
+	scope.GlobalScope.RegisterParams(ins.Name, ins.Params)
+}
+
+// Compile compiles the body of the function.
+func (ins *InstFunction) Compile(scope *Scope) {
+	if !ins.Body.Returns() {
+		fatalf(ins.Pos, "Function %s should RETURN at the end of every path", ins.Name)
+	}
+	function := scope.FindFunction(ins.Name)
+	funcScope := scope.NewFuncScope(function)
+	registerParams(funcScope, ins.Params)
+	ins.Body.Compile(funcScope)
+	// Every path has returned, so the last block could never be reached.
+	funcScope.Block.NewUnreachable()
+}
+
+// Compile compiles InstReturn
+// Instructions after RETURN are put into a new block,
+// which could never be reached.
+func (ins *InstReturn) Compile(scope *Scope) {
+	returnType := scope.Func.Sig.RetType
+	if types.IsVoid(returnType) || scope.Func == scope.FindFunction("main") {
+		fatalf(ins.Pos, "RETURN should be in a function")
+	}
+	returnVal := ConvertEval(scope, ins.Value.Evaluate(scope), returnType)
+	if returnVal == nil {
+		fatalf(ins.Pos, "RETURN a value of a wrong type")
+	}
+	scope.Block.NewRet(returnVal)
+	scope.Block = scope.Func.NewBlock("")
+}
+
+// Returns checks if every path of the ast ends with RETURN.
+func (ast *Ast) Returns() bool {
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.Return != nil:
+			return true
+		case inst.ConditionBr != nil:
+			if inst.ConditionBr.FalseBr != nil && inst.ConditionBr.TrueBr.Returns() && inst.ConditionBr.FalseBr.Returns() {
+				return true
+			}
+		case inst.Repeat != nil:
+			// The body of REPEAT runs at least once.
+			if inst.Repeat.Body.Returns() {
+				return true
+			}
+		case inst.Case != nil:
+			if inst.Case.Returns() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns checks if every clause of CASE ends with RETURN.
+// OTHERWISE is required, as the value might match none of the labels.
+func (ins *InstCase) Returns() bool {
+	if ins.Otherwise == nil || !ins.Otherwise.Returns() {
+		return false
+	}
+	for _, clause := range ins.Clauses {
+		if !clause.Body.Returns() {
+			return false
+		}
+	}
+	return true
+}
+
+// newIRParams creates the LLVM parameters of a procedure or a function.
+func newIRParams(scope *Scope, params []*Parameter) []*ir.Param {
+	irParams := make([]*ir.Param, len(params))
+	for index, param := range params {
+		irParams[index] = ir.NewParam(param.Name, param.IRType(scope))
+	}
+	return irParams
+}
+
+// registerParams registers the parameters as variables of the function scope.
+// Parameters passed BYVAL are copied to the stack, so that they are assignable.
+// Parameters passed BYREF are already pointers to the variables of the caller.
+func registerParams(funcScope *Scope, params []*Parameter) {
+	for index, param := range params {
+		var paramPtr value.Value = funcScope.Func.Params[index]
+		if !param.BYREF {
+			paramPtr = funcScope.NewLocal(paramPtr.Type())
+			funcScope.Block.NewStore(funcScope.Func.Params[index], paramPtr)
+		}
+		if err := funcScope.RegisterVariable(param.Name, paramPtr, &param.Type); err != nil {
+			fatalf(param.Pos, "%s", err)
+		}
+	}
+}
+
+// passArguments evaluates the arguments of a call to a procedure or a function.
+// Arguments passed BYREF should be variables,
+// and pointers to them are passed instead.
+func passArguments(scope *Scope, f *FunctionCall, params []*Parameter) []value.Value {
+	if len(f.Params) != len(params) {
+		fatalf(f.Pos, "%s takes %d arguments but %d are given", f.Name, len(params), len(f.Params))
+	}
+	arguments := make([]value.Value, len(params))
+	for index, param := range params {
+		paramType := param.Type.IRType(scope)
+		if param.BYREF {
+			key := f.Params[index].Key()
+			if key == nil {
+				fatalf(f.Pos, "Argument %s of %s is BYREF and should be a variable", param.Name, f.Name)
+			}
+			arguments[index] = key.Locate(scope)
+			if !types.Equal(arguments[index].Type(), types.NewPointer(paramType)) {
+				fatalf(f.Pos, "Argument %s of %s has a wrong type", param.Name, f.Name)
+			}
+			continue
+		}
+		arguments[index] = ConvertEval(scope, f.Params[index].Evaluate(scope), paramType)
+		if arguments[index] == nil {
+			fatalf(f.Pos, "Argument %s of %s has a wrong type", param.Name, f.Name)
+		}
+	}
+	return arguments
+}
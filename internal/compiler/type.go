@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+)
+
+// RecordType is a record type defined by TYPE.
+type RecordType struct {
+	Name   string
+	IRType *types.StructType
+	Fields []*InstDeclareVariable
+}
+
+// Field finds the field by name.
+// The index of the field in the struct and its type are given.
+// If the field is not found, -1 would be returned.
+func (r *RecordType) Field(name string) (int, *VariableType) {
+	for index, field := range r.Fields {
+		if field.Name == name {
+			return index, &field.Type
+		}
+	}
+	return -1, nil
+}
+
+// IRType gives the LLVM type of the variable type.
+// Record types are resolved via the scope.
+func (t *VariableType) IRType(scope *Scope) types.Type {
+	switch {
+	case t.ARRAY != nil:
+		return t.ARRAY.IRType(scope)
+	case t.Int != nil:
+		return types.I32
+	case t.REAL != nil:
+		return types.Double
+	case t.BOOL != nil:
+		return types.I1
+	case t.CHAR != nil:
+		return types.I8
+	case t.STRING != nil:
+		return stringPtrType
+	case t.DATE != nil:
+		return dateType
+	case t.CUSTOM != nil:
+		return t.Record(scope).IRType
+	}
+	fatalf(t.Pos, "Unknown type")
+	return nil
+}
+
+// Record gives the record type of the variable type.
+func (t *VariableType) Record(scope *Scope) *RecordType {
+	if t.CUSTOM == nil {
+		fatalf(t.Pos, "Not a record type")
+	}
+	recordType := scope.FindType(*t.CUSTOM)
+	if recordType == nil {
+		fatalf(t.Pos, "Unknown type %s", *t.CUSTOM)
+	}
+	return recordType
+}
+
+// Initial gives the value of a variable of the type before assigned.
+func (t *VariableType) Initial(scope *Scope) constant.Constant {
+	switch {
+	case t.ARRAY != nil, t.CUSTOM != nil, t.DATE != nil:
+		return constant.NewZeroInitializer(t.IRType(scope))
+	case t.Int != nil:
+		return constant.NewInt(types.I32, 0)
+	case t.REAL != nil:
+		return constant.NewFloat(types.Double, 0.0)
+	case t.BOOL != nil:
+		return constant.NewBool(false)
+	case t.CHAR != nil:
+		return constant.NewInt(types.I8, 0)
+	case t.STRING != nil:
+		// Null is the empty string for the runtime.
+		return constant.NewNull(stringPtrType)
+	}
+	fatalf(t.Pos, "Unknown type")
+	return nil
+}
+
+// IRType gives the LLVM type of the array.
+// Multi-dimensional arrays are nested arrays,
+// the first dimension is the outermost.
+func (a *ArrayType) IRType(scope *Scope) types.Type {
+	elemType := a.Element.IRType(scope)
+	for i := len(a.Dimensions) - 1; i >= 0; i-- {
+		elemType = types.NewArray(uint64(a.Dimensions[i].Length()), elemType)
+	}
+	return elemType
+}
+
+// Length gives the number of elements in the dimension.
+func (d *ArrayDimension) Length() int64 {
+	length := d.Upper.Int() - d.Lower.Int() + 1
+	if length <= 0 {
+		fatalf(d.Pos, "Upper bound of array is less than lower bound")
+	}
+	return length
+}
+
+// Int gives the value of the bound.
+func (b *ArrayBound) Int() int64 {
+	if b.Negative {
+		return -b.Value
+	}
+	return b.Value
+}
+
+// typeName gives the name of the type in pseudocode for messages.
+func typeName(typ types.Type) string {
+	switch {
+	case typ == types.I32:
+		return "INT"
+	case typ == types.Double:
+		return "REAL"
+	case typ == types.I1:
+		return "BOOL"
+	case typ == types.I8:
+		return "CHAR"
+	case types.Equal(typ, stringPtrType):
+		return "STRING"
+	case types.Equal(typ, dateType):
+		return "DATE"
+	}
+	switch typ := typ.(type) {
+	case *types.ArrayType:
+		return "ARRAY OF " + typeName(typ.ElemType)
+	case *types.StructType:
+		return typ.Name()
+	}
+	return typ.String()
+}
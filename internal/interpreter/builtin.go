@@ -0,0 +1,223 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuiltinFunction runs a function with the evaluated parameters.
+type BuiltinFunction func(rt *Runtime, params []Value) Value
+
+// builtinFunctions are functions of the syllabus.
+// They follow the builtinFunctions of the compiler.
+var builtinFunctions = map[string]BuiltinFunction{
+	"ASC": func(rt *Runtime, params []Value) Value {
+		return int32(params[0].(byte))
+	},
+	"CHR": func(rt *Runtime, params []Value) Value {
+		return byte(params[0].(int32))
+	},
+	"INT": func(rt *Runtime, params []Value) Value {
+		return int32(ToReal(params[0]))
+	},
+	"RAND": func(rt *Runtime, params []Value) Value {
+		return rt.rand(params[0].(int32))
+	},
+	"ROUND": func(rt *Runtime, params []Value) Value {
+		return round(ToReal(params[0]), params[1].(int32))
+	},
+}
+
+// RuntimeFunction runs a function of the runtime with the evaluated parameters.
+// An error is given if the arguments could not be converted for the function.
+type RuntimeFunction func(rt *Runtime, params []Value) (Value, error)
+
+// runtimeFunctions are the functions of the runtime which could be called in pseudocode.
+// They follow FindCFunction of the compiler, so functions of runtime.c are not included.
+// Functions working on pointers, such as scanf, are not supported.
+var runtimeFunctions = map[string]RuntimeFunction{
+	"puts": func(rt *Runtime, params []Value) (Value, error) {
+		s, err := cString(params[0])
+		if err != nil {
+			return nil, fmt.Errorf("Argument 1 of puts %s", err)
+		}
+		rt.Stdout.WriteString(s)
+		rt.Stdout.WriteByte('\n')
+		return int32(len(s) + 1), nil
+	},
+	"putchar": func(rt *Runtime, params []Value) (Value, error) {
+		i, err := toInt(params[0])
+		if err != nil {
+			return nil, fmt.Errorf("Argument 1 of putchar %s", err)
+		}
+		rt.Stdout.WriteByte(byte(i))
+		return int32(byte(i)), nil
+	},
+	"getchar": func(rt *Runtime, params []Value) (Value, error) {
+		// Lines of OUTPUT could be prompts of the input.
+		rt.Stdout.Flush()
+		c, err := rt.Stdin.ReadByte()
+		if err != nil {
+			return int32(-1), nil
+		}
+		return int32(c), nil
+	},
+	"printf": func(rt *Runtime, params []Value) (Value, error) {
+		format, err := cString(params[0])
+		if err != nil {
+			return nil, fmt.Errorf("Argument 1 of printf %s", err)
+		}
+		s, err := printf(format, params[1:])
+		if err != nil {
+			return nil, err
+		}
+		rt.Stdout.WriteString(s)
+		return int32(len(s)), nil
+	},
+}
+
+// Call calls a built-in function or a function of the runtime with the evaluated parameters.
+// The program stops if the function is not supported, or the arguments could not be converted.
+func (rt *Runtime) Call(name string, params []Value, line int) Value {
+	if function, ok := builtinFunctions[name]; ok {
+		return function(rt, params)
+	}
+	function, ok := runtimeFunctions[name]
+	if !ok {
+		rt.Fatalf("%s is not supported by the interpreter at line %d", name, line)
+	}
+	result, err := function(rt, params)
+	if err != nil {
+		rt.Fatalf("%s at line %d", err, line)
+	}
+	return result
+}
+
+// IsBuiltin checks if the name is a built-in function of the syllabus.
+func IsBuiltin(name string) bool {
+	_, ok := builtinFunctions[name]
+	return ok
+}
+
+// toInt converts a value passed as int to C functions,
+// which could be an INT, or a CHAR or a BOOL promoted like C does.
+func toInt(val Value) (int32, error) {
+	switch val := val.(type) {
+	case int32:
+		return val, nil
+	case byte:
+		return int32(val), nil
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("should be INT, CHAR or BOOL, not %s", typeName(val))
+}
+
+// toReal converts a value passed as double to C functions.
+func toReal(val Value) (float64, error) {
+	switch val := val.(type) {
+	case int32:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	}
+	return 0, fmt.Errorf("should be INT or REAL, not %s", typeName(val))
+}
+
+// cString gives the text of a STRING passed as char* to C functions.
+// The text ends at the first null character like C strings.
+func cString(val Value) (string, error) {
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("should be STRING, not %s", typeName(val))
+	}
+	if end := strings.IndexByte(s, 0); end >= 0 {
+		s = s[:end]
+	}
+	return s, nil
+}
+
+// printf formats the arguments like printf of C.
+// Conversions are handled by fmt, which agrees with C on the ones supported here.
+// An error is given if an argument does not suit its conversion.
+func printf(format string, args []Value) (string, error) {
+	var result strings.Builder
+	// argIndex is the index of the argument of the conversion, after the format.
+	argIndex := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			result.WriteByte(format[i])
+			continue
+		}
+		// Scan the conversion specification: flags, width, precision and length.
+		start := i
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0123456789.", format[j]) >= 0 {
+			j++
+		}
+		spec := format[i:j]
+		for j < len(format) && strings.IndexByte("hlLqjzt", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			result.WriteString(format[i:])
+			break
+		}
+		verb := format[j]
+		i = j
+		if verb == '%' {
+			result.WriteByte('%')
+			continue
+		}
+		var arg Value = int32(0)
+		argIndex++
+		if len(args) > 0 {
+			arg, args = args[0], args[1:]
+		}
+		var err error
+		switch verb {
+		case 'd', 'i':
+			var i int32
+			i, err = toInt(arg)
+			fmt.Fprintf(&result, spec+"d", i)
+		case 'u':
+			var i int32
+			i, err = toInt(arg)
+			fmt.Fprintf(&result, spec+"d", uint32(i))
+		case 'x', 'X', 'o':
+			var i int32
+			i, err = toInt(arg)
+			fmt.Fprintf(&result, spec+string(verb), uint32(i))
+		case 'c':
+			var i int32
+			i, err = toInt(arg)
+			result.WriteByte(byte(i))
+		case 's':
+			var str string
+			str, err = cString(arg)
+			fmt.Fprintf(&result, spec+"s", str)
+		case 'f', 'F', 'e', 'E':
+			var r float64
+			r, err = toReal(arg)
+			fmt.Fprintf(&result, spec+string(verb), r)
+		case 'g', 'G':
+			// fmt gives the shortest representation without a precision,
+			// while C gives 6 significant digits.
+			if !strings.Contains(spec, ".") {
+				spec += ".6"
+			}
+			var r float64
+			r, err = toReal(arg)
+			fmt.Fprintf(&result, spec+string(verb), r)
+		default:
+			result.WriteString(format[start : j+1])
+		}
+		if err != nil {
+			return "", fmt.Errorf("Argument %d of printf for %%%c %s", argIndex+1, verb, err)
+		}
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,57 @@
+package interpreter_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HankelBao/Pseudo/internal/pseudotest"
+)
+
+var corpus = flag.String("corpus", "../../test", "directory of the programs run by TestDifferential")
+
+// TestDifferential compiles every program in the corpus into LLVM IR,
+// and runs it with the interpreter, the bytecode VM,
+// and the executable linked with the runtime if clang is installed.
+// It fails if their outputs or exit codes differ.
+// A program reads its .in file as the input if there is one.
+func TestDifferential(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(*corpus, "*.pse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no programs in %s", *corpus)
+	}
+	hasClang := pseudotest.HasClang()
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			source, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stdin, err := ioutil.ReadFile(strings.TrimSuffix(file, ".pse") + ".in")
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+
+			ir := pseudotest.Compile(t, source)
+			interpreted := pseudotest.RunInterpreter(t, source, stdin)
+			if vm := pseudotest.RunVM(t, source, stdin); vm != interpreted {
+				t.Errorf("VM gives %+v, while the interpreter gives %+v", vm, interpreted)
+			}
+			if !hasClang {
+				t.Log("clang is not installed, the compiled program is not run")
+				return
+			}
+			if compiled := pseudotest.RunCompiled(t, ir, stdin); compiled != interpreted {
+				t.Errorf("compiled program gives %+v, while the interpreter gives %+v", compiled, interpreted)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package interpreter
+
+import (
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// evaluate evaluates the expression.
+// It follows Expression.Evaluate.
+func (in *Interpreter) evaluate(e *compiler.Expression, scope *Scope) Value {
+	return in.disjunction(&e.Disjunction, scope)
+}
+
+// disjunction evaluates OR.
+// The right side is evaluated only if the left side is FALSE.
+func (in *Interpreter) disjunction(d *compiler.Disjunction, scope *Scope) Value {
+	result := in.conjunction(&d.Head, scope)
+	for _, item := range d.Items {
+		if result.(bool) {
+			return true
+		}
+		result = in.conjunction(item, scope)
+	}
+	return result
+}
+
+// conjunction evaluates AND.
+// The right side is evaluated only if the left side is TRUE.
+func (in *Interpreter) conjunction(c *compiler.Conjunction, scope *Scope) Value {
+	result := in.negation(&c.Head, scope)
+	for _, item := range c.Items {
+		if !result.(bool) {
+			return false
+		}
+		result = in.negation(item, scope)
+	}
+	return result
+}
+
+// negation evaluates NOT.
+func (in *Interpreter) negation(n *compiler.Negation, scope *Scope) Value {
+	if n.Not != nil {
+		return !in.negation(n.Not, scope).(bool)
+	}
+	return in.comparison(n.Comparison, scope)
+}
+
+// comparison evaluates comparisons from left to right.
+func (in *Interpreter) comparison(c *compiler.Comparison, scope *Scope) Value {
+	lhsValue := in.addition(&c.Head, scope)
+	for _, item := range c.Items {
+		rhsValue := in.addition(&item.Item, scope)
+		lhsValue = Compare(item.Operator, lhsValue, rhsValue)
+	}
+	return lhsValue
+}
+
+// addition evaluates + and -.
+func (in *Interpreter) addition(a *compiler.Addition, scope *Scope) Value {
+	lhsValue := in.multiplication(&a.Head, scope)
+	for _, item := range a.Items {
+		rhsValue := in.multiplication(&item.Item, scope)
+		switch item.Operator {
+		case "+":
+			lhsValue = Add(lhsValue, rhsValue)
+		case "-":
+			lhsValue = Minus(lhsValue, rhsValue)
+		}
+	}
+	return lhsValue
+}
+
+// multiplication evaluates *, /, DIV and MOD.
+func (in *Interpreter) multiplication(m *compiler.Multiplication, scope *Scope) Value {
+	lhsValue := in.unary(&m.Head, scope)
+	for _, item := range m.Items {
+		rhsValue := in.unary(&item.Item, scope)
+		switch item.Operator {
+		case "*":
+			lhsValue = Multiple(lhsValue, rhsValue)
+		case "/":
+			lhsValue = Divide(lhsValue, rhsValue)
+		case "DIV":
+			lhsValue = in.IntDivide(item.Pos.Line, lhsValue, rhsValue)
+		case "MOD":
+			lhsValue = in.Mod(item.Pos.Line, lhsValue, rhsValue)
+		}
+	}
+	return lhsValue
+}
+
+// unary evaluates the opposite.
+func (in *Interpreter) unary(u *compiler.Unary, scope *Scope) Value {
+	if u.Opposite != nil {
+		return Opposite(in.unary(u.Opposite, scope))
+	}
+	return in.primary(u.Primary, scope)
+}
+
+// primary evaluates the smallest unit in an expression.
+func (in *Interpreter) primary(p *compiler.Primary, scope *Scope) Value {
+	switch {
+	case p.Constant != nil:
+		return constantValue(p.Constant)
+	case p.Key != nil:
+		return *in.locate(p.Key, scope)
+	case p.Function != nil:
+		return in.call(p.Function, scope)
+	case p.Subexpression != nil:
+		return in.evaluate(p.Subexpression, scope)
+	}
+	panic("unreachable")
+}
+
+// constantValue gets the value of the constant.
+func constantValue(c *compiler.Constant) Value {
+	switch {
+	case c.VString != nil:
+		return *c.VString
+	case c.VChar != nil:
+		// CHARs of more than one byte are rejected by Check.
+		char, _ := c.Char()
+		return char
+	case c.VReal != nil:
+		return *c.VReal
+	case c.VInt != nil:
+		return int32(*c.VInt)
+	case c.VBool != nil:
+		return *c.VBool == "TRUE"
+	}
+	panic("unreachable")
+}
+
+// locate gives the pointer to the value of the key.
+// It follows Key.Locate.
+func (in *Interpreter) locate(key *compiler.Key, scope *Scope) *Value {
+	ptr := in.locateVariable(key.Variables[0], scope)
+	for _, field := range key.Variables[1:] {
+		record := (*ptr).(*Record)
+		ptr = in.index(field, &record.Fields[record.Field(field.Name)], scope)
+	}
+	return ptr
+}
+
+// locateVariable gives the pointer to the variable with its indices applied.
+func (in *Interpreter) locateVariable(v *compiler.Variable, scope *Scope) *Value {
+	ptr := scope.FindVariable(v.Name)
+	if ptr == nil {
+		panic("variable not declared: " + v.Name)
+	}
+	return in.index(v, ptr, scope)
+}
+
+// index applies the indices to the array that ptr points to.
+// Each index is checked against the bounds once it is evaluated,
+// and the program stops if it is out of the bounds.
+func (in *Interpreter) index(v *compiler.Variable, ptr *Value, scope *Scope) *Value {
+	if len(v.Indices) == 0 {
+		return ptr
+	}
+	array := (*ptr).(*Array)
+	offset := int64(0)
+	for i, index := range v.Indices {
+		indexVal := int64(in.evaluate(index, scope).(int32))
+		dimension := array.Dimensions[i]
+		lower, upper := dimension.Lower.Int(), dimension.Upper.Int()
+		if indexVal < lower || indexVal > upper {
+			in.Fatalf("Index %d out of bounds [%d:%d] at line %d", indexVal, lower, upper, v.Pos.Line)
+		}
+		offset = offset*dimension.Length() + indexVal - lower
+	}
+	return &array.Elements[offset]
+}
@@ -0,0 +1,192 @@
+package interpreter
+
+import (
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// runAst runs the instructions in the scope.
+// It follows Ast.Compile.
+// The value of RETURN is given once it is reached, otherwise nil would be returned.
+func (in *Interpreter) runAst(ast *compiler.Ast, scope *Scope) Value {
+	for _, inst := range ast.Instructions {
+		var returned Value
+		switch {
+		case inst.Output != nil:
+			in.runOutput(inst.Output, scope)
+		case inst.Input != nil:
+			in.runInput(inst.Input, scope)
+		case inst.Call != nil:
+			in.runCall(inst.Call, scope)
+		case inst.DeclareVariable != nil:
+			in.runDeclareVariable(inst.DeclareVariable, scope)
+		case inst.TypeDefinition != nil, inst.Procedure != nil, inst.Function != nil:
+			// They have been handled by declare.
+		case inst.Return != nil:
+			returned = in.runReturn(inst.Return, scope)
+		case inst.Assignment != nil:
+			in.runAssignment(inst.Assignment, scope)
+		case inst.ConditionBr != nil:
+			returned = in.runConditionBr(inst.ConditionBr, scope)
+		case inst.While != nil:
+			returned = in.runWhile(inst.While, scope)
+		case inst.Repeat != nil:
+			returned = in.runRepeat(inst.Repeat, scope)
+		case inst.For != nil:
+			returned = in.runFor(inst.For, scope)
+		case inst.Case != nil:
+			returned = in.runCase(inst.Case, scope)
+		case inst.NullLine != nil:
+			continue
+		default:
+			panic("unknown instruction")
+		}
+		if returned != nil {
+			return returned
+		}
+	}
+	return nil
+}
+
+// runOutput outputs the items in a line.
+func (in *Interpreter) runOutput(ins *compiler.InstOutput, scope *Scope) {
+	for _, item := range ins.Items {
+		in.Output(in.evaluate(item, scope))
+	}
+	in.OutputNewline()
+}
+
+// runCall calls a procedure, or a function whose value is dropped.
+func (in *Interpreter) runCall(ins *compiler.InstCall, scope *Scope) {
+	if ins.Name != nil {
+		in.call(&compiler.FunctionCall{Pos: ins.Pos, Name: *ins.Name}, scope)
+		return
+	}
+	in.call(ins.Function, scope)
+}
+
+// runInput reads a line into the variable.
+func (in *Interpreter) runInput(ins *compiler.InstInput, scope *Scope) {
+	in.Input(in.locate(&ins.Content, scope), ins.Pos.Line)
+}
+
+// runDeclareVariable declares a private variable of the scope.
+// It is initialized each time the declaration is reached.
+// Global variables have been created by declare.
+func (in *Interpreter) runDeclareVariable(ins *compiler.InstDeclareVariable, scope *Scope) {
+	if scope.Main {
+		return
+	}
+	val := Initial(&ins.Type, scope)
+	scope.RegisterVariable(ins.Name, &val)
+}
+
+// runAssignment assigns the variable the value of the expression.
+func (in *Interpreter) runAssignment(ins *compiler.InstAssignment, scope *Scope) {
+	key := in.locate(&ins.Left, scope)
+	*key = Convert(in.evaluate(&ins.Right, scope), *key)
+}
+
+// runReturn gives the value returned by the function.
+func (in *Interpreter) runReturn(ins *compiler.InstReturn, scope *Scope) Value {
+	like := Initial(scope.Subroutine.ReturnType, scope)
+	return Convert(in.evaluate(&ins.Value, scope), like)
+}
+
+// runConditionBr runs one of the branches.
+func (in *Interpreter) runConditionBr(ins *compiler.InstConditionBr, scope *Scope) Value {
+	if in.evaluate(&ins.Condition, scope).(bool) {
+		return in.runAst(&ins.TrueBr, scope.NewScope())
+	}
+	if ins.FalseBr != nil {
+		return in.runAst(ins.FalseBr, scope.NewScope())
+	}
+	return nil
+}
+
+// runWhile runs the body while the condition is true.
+func (in *Interpreter) runWhile(ins *compiler.InstWhile, scope *Scope) Value {
+	for in.evaluate(&ins.Condition, scope).(bool) {
+		if returned := in.runAst(&ins.Body, scope.NewScope()); returned != nil {
+			return returned
+		}
+	}
+	return nil
+}
+
+// runRepeat runs the body until the condition is true.
+// The condition is in the scope of the body.
+func (in *Interpreter) runRepeat(ins *compiler.InstRepeat, scope *Scope) Value {
+	for {
+		bodyScope := scope.NewScope()
+		if returned := in.runAst(&ins.Body, bodyScope); returned != nil {
+			return returned
+		}
+		if in.evaluate(&ins.Condition, bodyScope).(bool) {
+			return nil
+		}
+	}
+}
+
+// runFor runs the counted loop.
+// End and step are evaluated only once before the loop,
+// and the loop counts down for negative steps.
+func (in *Interpreter) runFor(ins *compiler.InstFor, scope *Scope) Value {
+	counter := in.locateVariable(&compiler.Variable{Pos: ins.Pos, Name: ins.Counter}, scope)
+	start := Convert(in.evaluate(&ins.Start, scope), *counter)
+	end := Convert(in.evaluate(&ins.End, scope), *counter)
+	var zero, step Value = int32(0), int32(1)
+	if _, ok := (*counter).(float64); ok {
+		zero, step = float64(0), float64(1)
+	}
+	if ins.Step != nil {
+		step = Convert(in.evaluate(ins.Step, scope), *counter)
+	}
+	ascending := Compare(">=", step, zero)
+	*counter = start
+
+	for {
+		if ascending && !Compare("<=", *counter, end) || !ascending && !Compare(">=", *counter, end) {
+			return nil
+		}
+		if returned := in.runAst(&ins.Body, scope.NewScope()); returned != nil {
+			return returned
+		}
+		*counter = Add(*counter, step)
+	}
+}
+
+// runCase runs the first clause matching the value,
+// or OTHERWISE if none of them matches.
+func (in *Interpreter) runCase(ins *compiler.InstCase, scope *Scope) Value {
+	caseVal := in.evaluate(&ins.Value, scope)
+	for _, clause := range ins.Clauses {
+		for _, label := range clause.Labels {
+			if in.match(label, caseVal) {
+				return in.runAst(&clause.Body, scope.NewScope())
+			}
+		}
+	}
+	if ins.Otherwise != nil {
+		return in.runAst(ins.Otherwise, scope.NewScope())
+	}
+	return nil
+}
+
+// match checks if the value matches the label.
+func (in *Interpreter) match(label *compiler.CaseLabel, caseVal Value) bool {
+	from := Convert(in.caseValue(&label.From), caseVal)
+	if label.To == nil {
+		return Compare("=", caseVal, from)
+	}
+	to := Convert(in.caseValue(label.To), caseVal)
+	return Compare(">=", caseVal, from) && Compare("<=", caseVal, to)
+}
+
+// caseValue gets the value of the constant in the label.
+func (in *Interpreter) caseValue(v *compiler.CaseValue) Value {
+	val := constantValue(&v.Constant)
+	if v.Negative {
+		return Opposite(val)
+	}
+	return val
+}
@@ -0,0 +1,67 @@
+package interpreter
+
+import (
+	"io"
+
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// Interpreter runs the ast directly instead of compiling it,
+// so that programs could be run without clang.
+// It behaves the same as the compiled program with the runtime,
+// including the format of OUTPUT and the messages of errors.
+type Interpreter struct {
+	*Runtime
+
+	// depth is the number of subroutines being called.
+	depth int
+}
+
+// New creates an interpreter with the input and outputs of the program.
+func New(stdin io.Reader, stdout io.Writer, stderr io.Writer) *Interpreter {
+	return &Interpreter{Runtime: NewRuntime(stdin, stdout, stderr)}
+}
+
+// Run runs the ast and gives the exit code of the program.
+// The ast is checked first, so that the interpreter accepts the same programs as the compiler,
+// and all the errors are given as Diagnostics.
+func (in *Interpreter) Run(ast *compiler.Ast) (code int, err error) {
+	if diagnostics := compiler.Check(ast); len(diagnostics) != 0 {
+		return 0, diagnostics
+	}
+	defer in.Finish(&code, &err)
+
+	globalScope := NewGlobalScope()
+	mainScope := globalScope.NewScope()
+	mainScope.Main = true
+	in.declare(ast, mainScope)
+	in.runAst(ast, mainScope)
+	return 0, nil
+}
+
+// declare registers types and subroutines, and creates global variables,
+// so that they could be used before they are defined.
+// It follows Ast.Declare.
+func (in *Interpreter) declare(ast *compiler.Ast, scope *Scope) {
+	for _, inst := range ast.Instructions {
+		switch {
+		case inst.TypeDefinition != nil:
+			scope.RegisterType(inst.TypeDefinition)
+		case inst.Procedure != nil:
+			ins := inst.Procedure
+			scope.RegisterFunction(&Subroutine{Name: ins.Name, Params: ins.Params, Body: &ins.Body})
+		case inst.Function != nil:
+			ins := inst.Function
+			scope.RegisterFunction(&Subroutine{Name: ins.Name, Params: ins.Params, Body: &ins.Body, ReturnType: &ins.ReturnType})
+		}
+	}
+	// Global variables are initialized before the program starts,
+	// so that subroutines called before their declarations could use them.
+	// The compiler has made sure the main block never uses them before.
+	for _, inst := range ast.Instructions {
+		if inst.DeclareVariable != nil {
+			val := Initial(&inst.DeclareVariable.Type, scope)
+			scope.GlobalScope.RegisterVariable(inst.DeclareVariable.Name, &val)
+		}
+	}
+}
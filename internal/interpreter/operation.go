@@ -0,0 +1,160 @@
+package interpreter
+
+import (
+	"math"
+	"strings"
+)
+
+// Add evaluates +
+// It follows AddEval.
+func Add(value1 Value, value2 Value) Value {
+	value1, value2 = unify(value1, value2)
+	if i, ok := value1.(int32); ok {
+		return i + value2.(int32)
+	}
+	return value1.(float64) + value2.(float64)
+}
+
+// Minus evaluates -
+func Minus(value1 Value, value2 Value) Value {
+	value1, value2 = unify(value1, value2)
+	if i, ok := value1.(int32); ok {
+		return i - value2.(int32)
+	}
+	return value1.(float64) - value2.(float64)
+}
+
+// Multiple evaluates *
+func Multiple(value1 Value, value2 Value) Value {
+	value1, value2 = unify(value1, value2)
+	if i, ok := value1.(int32); ok {
+		return i * value2.(int32)
+	}
+	return value1.(float64) * value2.(float64)
+}
+
+// Divide evaluates /
+// The result is always REAL, so INTs are converted first.
+func Divide(value1 Value, value2 Value) Value {
+	return ToReal(value1) / ToReal(value2)
+}
+
+// IntDivide evaluates DIV of INTs.
+// It stops with an error where the compiled program calls pseudo_division_error.
+func (rt *Runtime) IntDivide(line int, value1 Value, value2 Value) Value {
+	rt.checkDivisor(line, value1.(int32), value2.(int32))
+	return value1.(int32) / value2.(int32)
+}
+
+// Mod evaluates MOD of INTs.
+// The sign of the result is the same as the left side, like srem.
+func (rt *Runtime) Mod(line int, value1 Value, value2 Value) Value {
+	rt.checkDivisor(line, value1.(int32), value2.(int32))
+	return value1.(int32) % value2.(int32)
+}
+
+// checkDivisor stops the program if the divisor is zero,
+// or the division is INT_MIN DIV -1. It follows checkDivisor of the compiler.
+func (rt *Runtime) checkDivisor(line int, value1 int32, value2 int32) {
+	if value2 == 0 {
+		rt.Fatalf("Division by zero at line %d", line)
+	}
+	if value1 == math.MinInt32 && value2 == -1 {
+		rt.Fatalf("Integer overflow at line %d", line)
+	}
+}
+
+// Opposite evaluates the opposite.
+// REALs are subtracted from zero like OppositeEval, so -0.0 is never given.
+func Opposite(val Value) Value {
+	if i, ok := val.(int32); ok {
+		return -i
+	}
+	return 0 - val.(float64)
+}
+
+// ToReal converts an INT to REAL.
+func ToReal(val Value) float64 {
+	if i, ok := val.(int32); ok {
+		return float64(i)
+	}
+	return val.(float64)
+}
+
+// unify converts an INT to REAL if the other value is a REAL,
+// so that both values are of the same type.
+// It follows unifyEval.
+func unify(value1 Value, value2 Value) (Value, Value) {
+	_, real1 := value1.(float64)
+	_, real2 := value2.(float64)
+	if real1 || real2 {
+		return ToReal(value1), ToReal(value2)
+	}
+	return value1, value2
+}
+
+// Compare evaluates the comparison.
+// It follows the Cmp functions of the compiler:
+// CHARs are compared by their unsigned codes,
+// STRINGs are compared like strcmp,
+// and comparisons with NaN are false.
+func Compare(operator string, value1 Value, value2 Value) bool {
+	value1, value2 = unify(value1, value2)
+	var order int
+	switch value1 := value1.(type) {
+	case int32:
+		order = compareInt(int64(value1), int64(value2.(int32)))
+	case byte:
+		order = compareInt(int64(value1), int64(value2.(byte)))
+	case Date:
+		order = compareInt(int64(value1), int64(value2.(Date)))
+	case string:
+		order = strings.Compare(value1, value2.(string))
+	case bool:
+		// BOOLs could only be compared for equality.
+		equal := value1 == value2.(bool)
+		return equal == (operator == "=")
+	case float64:
+		value2 := value2.(float64)
+		switch operator {
+		case "=":
+			return value1 == value2
+		case "<>":
+			return value1 < value2 || value1 > value2
+		case "<":
+			return value1 < value2
+		case "<=":
+			return value1 <= value2
+		case ">":
+			return value1 > value2
+		case ">=":
+			return value1 >= value2
+		}
+	}
+	switch operator {
+	case "=":
+		return order == 0
+	case "<>":
+		return order != 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+	panic("unknown operator " + operator)
+}
+
+// compareInt gives the order of two integers.
+func compareInt(i int64, j int64) int {
+	switch {
+	case i < j:
+		return -1
+	case i > j:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,301 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Runtime mirrors runtime.c,
+// so that programs run without clang give the same output and errors as compiled programs.
+// It is shared by the interpreter and the bytecode VM.
+type Runtime struct {
+	Stdin  *bufio.Reader
+	Stdout *bufio.Writer
+	Stderr io.Writer
+
+	random *rand.Rand
+}
+
+// MaxCallDepth is the maximum number of subroutines being called at once.
+// Deeper recursions stop with an error instead of exhausting the stack of Go.
+const MaxCallDepth = 100000
+
+// Exit stops the program with the exit code, like exit() of the runtime.
+// It is raised as a panic and recovered by Finish.
+type Exit struct {
+	Code int
+}
+
+// NewRuntime creates a runtime with the input and outputs of the program.
+func NewRuntime(stdin io.Reader, stdout io.Writer, stderr io.Writer) *Runtime {
+	return &Runtime{
+		Stdin:  bufio.NewReader(stdin),
+		Stdout: bufio.NewWriter(stdout),
+		Stderr: stderr,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Finish should be deferred by the runner of the program.
+// It gives the exit code if the program is stopped by Exit, and flushes the output.
+// Any other panic is a bug of the runner, which is given as the error
+// instead of crashing the host of the program.
+func (rt *Runtime) Finish(code *int, err *error) {
+	if r := recover(); r != nil {
+		if exit, ok := r.(Exit); ok {
+			*code = exit.Code
+		} else {
+			*code, *err = 0, fmt.Errorf("internal error: %v", r)
+		}
+	}
+	rt.Stdout.Flush()
+}
+
+// Fatalf stops the program with an error, like the runtime does.
+// Lines of OUTPUT are flushed before the error.
+func (rt *Runtime) Fatalf(format string, args ...interface{}) {
+	rt.Stdout.Flush()
+	fmt.Fprintf(rt.Stderr, format+"\n", args...)
+	panic(Exit{Code: 1})
+}
+
+// Output outputs a value of OUTPUT.
+func (rt *Runtime) Output(val Value) {
+	switch val := val.(type) {
+	case string:
+		rt.Stdout.WriteString(val)
+	case byte:
+		rt.Stdout.WriteByte(val)
+	case int32:
+		rt.Stdout.WriteString(strconv.Itoa(int(val)))
+	case float64:
+		rt.Stdout.WriteString(formatReal(val))
+	case bool:
+		if val {
+			rt.Stdout.WriteString("TRUE")
+		} else {
+			rt.Stdout.WriteString("FALSE")
+		}
+	case Date:
+		fmt.Fprintf(rt.Stdout, "%02d/%02d/%04d", val%100, val/100%100, val/10000)
+	default:
+		panic("OUTPUT a value which is not scalar")
+	}
+}
+
+// OutputNewline ends the line of OUTPUT.
+func (rt *Runtime) OutputNewline() {
+	rt.Stdout.WriteByte('\n')
+}
+
+// formatReal formats a REAL like "%.15g" of C.
+// Whole numbers keep a decimal point, such as 3.0,
+// so that they could be told from INTs.
+func formatReal(r float64) string {
+	var s string
+	switch {
+	case math.IsInf(r, 1):
+		s = "inf"
+	case math.IsInf(r, -1):
+		s = "-inf"
+	case math.IsNaN(r):
+		s = "nan"
+	default:
+		s = strconv.FormatFloat(r, 'g', 15, 64)
+	}
+	if !strings.ContainsAny(s, ".eni") {
+		s += ".0"
+	}
+	return s
+}
+
+// rand gives a random REAL in [0, max).
+func (rt *Runtime) rand(max int32) float64 {
+	return rt.random.Float64() * float64(max)
+}
+
+// round rounds a REAL to the number of decimal places.
+// Halves are rounded away from zero.
+// Negative places round to tens, hundreds and so on.
+func round(r float64, places int32) float64 {
+	scale := 1.0
+	for i := int32(0); i < places; i++ {
+		scale *= 10.0
+	}
+	for i := int32(0); i > places; i-- {
+		scale /= 10.0
+	}
+	scaled := r * scale
+	// Numbers this large have no fraction part to round.
+	if scaled >= 9e18 || scaled <= -9e18 || scaled != scaled {
+		return r
+	}
+	var rounded int64
+	if scaled < 0 {
+		rounded = int64(scaled - 0.5)
+	} else {
+		rounded = int64(scaled + 0.5)
+	}
+	return float64(rounded) / scale
+}
+
+// Input reads a line into the variable that ptr points to.
+// The line is converted according to the type of the variable.
+func (rt *Runtime) Input(ptr *Value, line int) {
+	input := rt.inputLine(line)
+	switch (*ptr).(type) {
+	case int32:
+		*ptr = rt.parseInt(input, line)
+	case float64:
+		*ptr = rt.parseReal(input, line)
+	case bool:
+		*ptr = rt.parseBool(input, line)
+	case byte:
+		*ptr = rt.parseChar(input, line)
+	case string:
+		*ptr = input
+	case Date:
+		*ptr = rt.parseDate(input, line)
+	default:
+		panic("INPUT into a variable which is not scalar")
+	}
+}
+
+// inputLine reads a line without the line break.
+// The program stops if there is no more input.
+func (rt *Runtime) inputLine(line int) string {
+	// Lines of OUTPUT could be prompts of the input.
+	rt.Stdout.Flush()
+	data, err := rt.Stdin.ReadString('\n')
+	if err == io.EOF && data == "" {
+		rt.Fatalf("No more input at line %d", line)
+	}
+	data = strings.TrimSuffix(data, "\n")
+	data = strings.TrimSuffix(data, "\r")
+	return data
+}
+
+// inputError stops the program when the input could not be converted.
+func (rt *Runtime) inputError(typ string, s string, line int) {
+	rt.Fatalf("Invalid %s input '%s' at line %d", typ, s, line)
+}
+
+// trim removes the spaces around the input, like isspace of C.
+func trim(s string) string {
+	return strings.Trim(s, " \t\n\v\f\r")
+}
+
+// scanDigits gives the number of digits at the start of s.
+func scanDigits(s string) int {
+	count := 0
+	for count < len(s) && s[count] >= '0' && s[count] <= '9' {
+		count++
+	}
+	return count
+}
+
+// parseInt converts the input to INT.
+// It should be digits with an optional sign.
+func (rt *Runtime) parseInt(s string, line int) int32 {
+	trimmed := trim(s)
+	p := trimmed
+	if p != "" && (p[0] == '+' || p[0] == '-') {
+		p = p[1:]
+	}
+	if digits := scanDigits(p); digits == 0 || digits != len(p) || len(trimmed) > 12 {
+		rt.inputError("INT", s, line)
+	}
+	value, err := strconv.ParseInt(trimmed, 10, 32)
+	if err != nil {
+		rt.inputError("INT", s, line)
+	}
+	return int32(value)
+}
+
+// parseReal converts the input to REAL.
+// It should be a decimal number with an optional exponent.
+func (rt *Runtime) parseReal(s string, line int) float64 {
+	trimmed := trim(s)
+	p := trimmed
+	if p != "" && (p[0] == '+' || p[0] == '-') {
+		p = p[1:]
+	}
+	digits := scanDigits(p)
+	p = p[digits:]
+	if p != "" && p[0] == '.' {
+		p = p[1:]
+		fraction := scanDigits(p)
+		digits += fraction
+		p = p[fraction:]
+	}
+	if digits > 0 && p != "" && (p[0] == 'e' || p[0] == 'E') {
+		p = p[1:]
+		if p != "" && (p[0] == '+' || p[0] == '-') {
+			p = p[1:]
+		}
+		exponent := scanDigits(p)
+		if exponent == 0 {
+			digits = 0
+		}
+		p = p[exponent:]
+	}
+	if digits == 0 || p != "" {
+		rt.inputError("REAL", s, line)
+	}
+	// Numbers out of range become infinities like strtod.
+	value, _ := strconv.ParseFloat(trimmed, 64)
+	return value
+}
+
+// parseBool converts the input to BOOL.
+// It should be TRUE or FALSE in any case.
+func (rt *Runtime) parseBool(s string, line int) bool {
+	trimmed := trim(s)
+	switch {
+	case strings.EqualFold(trimmed, "TRUE"):
+		return true
+	case strings.EqualFold(trimmed, "FALSE"):
+		return false
+	}
+	rt.inputError("BOOL", s, line)
+	return false
+}
+
+// parseChar converts the input to CHAR.
+// It should be exactly one character.
+func (rt *Runtime) parseChar(s string, line int) byte {
+	if len(s) != 1 {
+		rt.inputError("CHAR", s, line)
+	}
+	return s[0]
+}
+
+// parseDate converts the input to DATE.
+// It should be in the format of DD/MM/YYYY.
+func (rt *Runtime) parseDate(s string, line int) Date {
+	days := []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+	parts := strings.Split(trim(s), "/")
+	if len(parts) != 3 {
+		rt.inputError("DATE", s, line)
+	}
+	for index, part := range parts {
+		digits := scanDigits(part)
+		if digits != len(part) || digits < 1 || digits > 2 && index < 2 || digits != 4 && index == 2 {
+			rt.inputError("DATE", s, line)
+		}
+	}
+	day, _ := strconv.Atoi(parts[0])
+	month, _ := strconv.Atoi(parts[1])
+	year, _ := strconv.Atoi(parts[2])
+	leap := year%4 == 0 && year%100 != 0 || year%400 == 0
+	if month < 1 || month > 12 || day < 1 || day > days[month-1] || month == 2 && day == 29 && !leap {
+		rt.inputError("DATE", s, line)
+	}
+	return Date(year*10000 + month*100 + day)
+}
@@ -0,0 +1,116 @@
+package interpreter
+
+import (
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// ScopeVariableMap is a map to store all the variables in a scope.
+// Variables are pointers to their values,
+// so that they could be passed BYREF.
+type ScopeVariableMap map[string]*Value
+
+// ScopeFuncMap is a map to store the procedures and functions defined in pseudocode.
+type ScopeFuncMap map[string]*Subroutine
+
+// ScopeTypeMap is a map to store all the record types.
+type ScopeTypeMap map[string]*compiler.InstTypeDefinition
+
+// Subroutine is a procedure or a function defined in pseudocode.
+type Subroutine struct {
+	Name   string
+	Params []*compiler.Parameter
+	Body   *compiler.Ast
+	// ReturnType is nil for procedures.
+	ReturnType *compiler.VariableType
+}
+
+// Scope keeps track of the variables in a block while running.
+// It mirrors the Scope of the compiler.
+type Scope struct {
+	Variables ScopeVariableMap
+	Functions ScopeFuncMap
+	Types     ScopeTypeMap
+
+	// Variables declared in the main block are global variables,
+	// which have been created before running.
+	Main bool
+	// Subroutine is the procedure or function running,
+	// which is nil in the main block.
+	Subroutine *Subroutine
+
+	GlobalScope *Scope
+	Parent      *Scope
+}
+
+// NewGlobalScope creates a global scope.
+// There should be only one global scope.
+func NewGlobalScope() *Scope {
+	scope := &Scope{
+		Variables: make(ScopeVariableMap),
+		Functions: make(ScopeFuncMap),
+		Types:     make(ScopeTypeMap),
+	}
+	scope.GlobalScope = scope
+	return scope
+}
+
+// NewFuncScope creates the scope of a call to the subroutine under global scope.
+func (scope *Scope) NewFuncScope(subroutine *Subroutine) *Scope {
+	return &Scope{
+		Variables:   make(ScopeVariableMap),
+		Subroutine:  subroutine,
+		GlobalScope: scope.GlobalScope,
+		Parent:      scope.GlobalScope,
+	}
+}
+
+// NewScope creates a new scope under the given scope.
+func (scope *Scope) NewScope() *Scope {
+	return &Scope{
+		Variables:   make(ScopeVariableMap),
+		Subroutine:  scope.Subroutine,
+		GlobalScope: scope.GlobalScope,
+		Parent:      scope,
+	}
+}
+
+// RegisterVariable registers a variable to the current scope.
+// The program has been compiled, so it is never defined twice.
+func (scope *Scope) RegisterVariable(name string, val *Value) {
+	scope.Variables[name] = val
+}
+
+// FindVariable locates the variable registered.
+// If the variable is not found, nil would be returned.
+func (scope *Scope) FindVariable(name string) *Value {
+	for currentScope := scope; currentScope != nil; currentScope = currentScope.Parent {
+		if val, ok := currentScope.Variables[name]; ok {
+			return val
+		}
+	}
+	return nil
+}
+
+// RegisterFunction registers a procedure or a function.
+// Functions should be registered to global scope only!
+func (scope *Scope) RegisterFunction(subroutine *Subroutine) {
+	scope.GlobalScope.Functions[subroutine.Name] = subroutine
+}
+
+// FindFunction locates the procedure or the function.
+// If it is not defined in pseudocode, nil would be returned.
+func (scope *Scope) FindFunction(name string) *Subroutine {
+	return scope.GlobalScope.Functions[name]
+}
+
+// RegisterType registers a record type.
+// Types should be registered to global scope only!
+func (scope *Scope) RegisterType(recordType *compiler.InstTypeDefinition) {
+	scope.GlobalScope.Types[recordType.Name] = recordType
+}
+
+// FindType locates the record type.
+// If the type is not found, nil would be returned.
+func (scope *Scope) FindType(name string) *compiler.InstTypeDefinition {
+	return scope.GlobalScope.Types[name]
+}
@@ -0,0 +1,50 @@
+package interpreter
+
+import (
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// call calls a built-in function, a subroutine or a function of the runtime.
+// It follows FunctionCall.Compile.
+// nil would be returned for procedures.
+func (in *Interpreter) call(f *compiler.FunctionCall, scope *Scope) Value {
+	if !IsBuiltin(f.Name) {
+		if subroutine := scope.FindFunction(f.Name); subroutine != nil {
+			return in.callSubroutine(f, subroutine, scope)
+		}
+	}
+
+	params := make([]Value, len(f.Params))
+	for index, item := range f.Params {
+		params[index] = in.evaluate(item, scope)
+	}
+	return in.Call(f.Name, params, f.Pos.Line)
+}
+
+// callSubroutine runs the body of the subroutine in a new scope.
+// Arguments passed BYREF are pointers to the variables of the caller,
+// and arguments passed BYVAL are copied.
+// The program stops if the calls are nested deeper than MaxCallDepth.
+func (in *Interpreter) callSubroutine(f *compiler.FunctionCall, subroutine *Subroutine, scope *Scope) Value {
+	if in.depth >= MaxCallDepth {
+		in.Fatalf("Stack overflow at line %d", f.Pos.Line)
+	}
+	arguments := make([]*Value, len(subroutine.Params))
+	for index, param := range subroutine.Params {
+		if param.BYREF {
+			arguments[index] = in.locate(f.Params[index].Key(), scope)
+			continue
+		}
+		like := Initial(&param.Type, scope)
+		val := Convert(in.evaluate(f.Params[index], scope), like)
+		arguments[index] = &val
+	}
+
+	funcScope := scope.NewFuncScope(subroutine)
+	for index, param := range subroutine.Params {
+		funcScope.RegisterVariable(param.Name, arguments[index])
+	}
+	in.depth++
+	defer func() { in.depth-- }()
+	return in.runAst(subroutine.Body, funcScope)
+}
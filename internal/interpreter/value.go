@@ -0,0 +1,140 @@
+package interpreter
+
+import (
+	"github.com/HankelBao/Pseudo/internal/compiler"
+)
+
+// Value is a value in pseudocode.
+// It is one of:
+// - int32 for INT
+// - float64 for REAL
+// - bool for BOOL
+// - byte for CHAR
+// - string for STRING
+// - Date for DATE
+// - *Array for arrays
+// - *Record for records
+type Value interface{}
+
+// Date is the representation of DATE.
+// Like the runtime, it is stored as YYYYMMDD so that dates could be compared as integers.
+type Date int32
+
+// typeName gives the name of the type of the value in pseudocode for messages.
+func typeName(val Value) string {
+	switch val := val.(type) {
+	case int32:
+		return "INT"
+	case float64:
+		return "REAL"
+	case bool:
+		return "BOOL"
+	case byte:
+		return "CHAR"
+	case string:
+		return "STRING"
+	case Date:
+		return "DATE"
+	case *Array:
+		return "ARRAY"
+	case *Record:
+		return val.Type.Name
+	}
+	return "nothing"
+}
+
+// Array is the value of an array.
+// Elements of all the dimensions are flattened, the first dimension is the outermost.
+type Array struct {
+	Dimensions []*compiler.ArrayDimension
+	Elements   []Value
+}
+
+// Record is the value of a record type defined by TYPE.
+// Fields are in the order of their declarations.
+type Record struct {
+	Type   *compiler.InstTypeDefinition
+	Fields []Value
+}
+
+// Field finds the index of the field by name.
+// If the field is not found, -1 would be returned.
+func (r *Record) Field(name string) int {
+	for index, field := range r.Type.Fields {
+		if field.Name == name {
+			return index
+		}
+	}
+	return -1
+}
+
+// Initial gives the value of a variable of the type before assigned.
+// It follows VariableType.Initial.
+func Initial(t *compiler.VariableType, scope *Scope) Value {
+	switch {
+	case t.ARRAY != nil:
+		length := int64(1)
+		for _, dimension := range t.ARRAY.Dimensions {
+			length *= dimension.Length()
+		}
+		array := &Array{Dimensions: t.ARRAY.Dimensions, Elements: make([]Value, length)}
+		for index := range array.Elements {
+			array.Elements[index] = Initial(t.ARRAY.Element, scope)
+		}
+		return array
+	case t.Int != nil:
+		return int32(0)
+	case t.REAL != nil:
+		return float64(0)
+	case t.BOOL != nil:
+		return false
+	case t.CHAR != nil:
+		return byte(0)
+	case t.STRING != nil:
+		return ""
+	case t.DATE != nil:
+		return Date(0)
+	case t.CUSTOM != nil:
+		recordType := scope.FindType(*t.CUSTOM)
+		if recordType == nil {
+			panic("unknown type " + *t.CUSTOM)
+		}
+		record := &Record{Type: recordType, Fields: make([]Value, len(recordType.Fields))}
+		for index, field := range recordType.Fields {
+			record.Fields[index] = Initial(&field.Type, scope)
+		}
+		return record
+	}
+	panic("unknown type")
+}
+
+// Copy copies the value when it is stored,
+// since arrays and records are values rather than references in pseudocode.
+func Copy(val Value) Value {
+	switch val := val.(type) {
+	case *Array:
+		array := &Array{Dimensions: val.Dimensions, Elements: make([]Value, len(val.Elements))}
+		for index, element := range val.Elements {
+			array.Elements[index] = Copy(element)
+		}
+		return array
+	case *Record:
+		record := &Record{Type: val.Type, Fields: make([]Value, len(val.Fields))}
+		for index, field := range val.Fields {
+			record.Fields[index] = Copy(field)
+		}
+		return record
+	}
+	return val
+}
+
+// Convert converts the value to the type of like when it is stored.
+// It follows ConvertEval, which converts INT to REAL only.
+func Convert(val Value, like Value) Value {
+	if i, ok := val.(int32); ok {
+		if _, ok := like.(float64); ok {
+			return float64(i)
+		}
+	}
+	return Copy(val)
+}
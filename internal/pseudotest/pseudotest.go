@@ -0,0 +1,128 @@
+// Package pseudotest runs programs with the compiler, the interpreter and the bytecode VM for tests.
+package pseudotest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/HankelBao/Pseudo/internal/bytecode"
+	"github.com/HankelBao/Pseudo/internal/compiler"
+	"github.com/HankelBao/Pseudo/internal/interpreter"
+)
+
+// Result is what a program gives when it is run.
+type Result struct {
+	Stdout string
+	Stderr string
+	Code   int
+}
+
+// Golden gives the result in the format of .out files,
+// which is stdout, followed by stderr and the exit code if the program fails.
+func (r Result) Golden() string {
+	if r.Code != 0 || r.Stderr != "" {
+		return fmt.Sprintf("%s-- stderr --\n%s-- exit status %d --\n", r.Stdout, r.Stderr, r.Code)
+	}
+	return r.Stdout
+}
+
+// HasClang checks if clang is installed, which is needed by RunCompiled.
+func HasClang() bool {
+	_, err := exec.LookPath("clang")
+	return err == nil
+}
+
+// Parse parses the program, and the test stops if it could not be parsed.
+func Parse(t testing.TB, source []byte) *compiler.Ast {
+	t.Helper()
+	ast, err := compiler.Parse(bytes.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	return ast
+}
+
+// Compile compiles the program into LLVM IR.
+// The test stops if it could not be compiled or the IR is empty.
+func Compile(t testing.TB, source []byte) string {
+	t.Helper()
+	module, err := compiler.Compile(Parse(t, source))
+	if err != nil {
+		t.Fatalf("Compile: %s", err)
+	}
+	ir := module.String()
+	if ir == "" {
+		t.Fatal("Compile: empty IR")
+	}
+	return ir
+}
+
+// RunCompiled links the IR given by Compile with the runtime by clang,
+// and runs the executable in a temporary directory.
+func RunCompiled(t testing.TB, ir string, stdin []byte) Result {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "pseudo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	irFile := filepath.Join(dir, "program.ll")
+	runtimeFile := filepath.Join(dir, "runtime.c")
+	executable := filepath.Join(dir, "program")
+	if err := ioutil.WriteFile(irFile, []byte(ir), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(runtimeFile, []byte(compiler.RuntimeSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := exec.Command("clang", irFile, runtimeFile, "-o", executable).CombinedOutput(); err != nil {
+		t.Fatalf("clang: %s\n%s", err, output)
+	}
+
+	var stdout, stderr bytes.Buffer
+	programCmd := exec.Command(executable)
+	programCmd.Stdin = bytes.NewReader(stdin)
+	programCmd.Stdout = &stdout
+	programCmd.Stderr = &stderr
+	code := 0
+	if err := programCmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatal(err)
+		}
+		code = exitErr.ExitCode()
+	}
+	return Result{Stdout: stdout.String(), Stderr: stderr.String(), Code: code}
+}
+
+// RunInterpreter runs the program with the interpreter.
+func RunInterpreter(t testing.TB, source []byte, stdin []byte) Result {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code, err := interpreter.New(bytes.NewReader(stdin), &stdout, &stderr).Run(Parse(t, source))
+	if err != nil {
+		t.Fatalf("interpreter: %s", err)
+	}
+	return Result{Stdout: stdout.String(), Stderr: stderr.String(), Code: code}
+}
+
+// RunVM compiles the program into bytecode and runs it with the VM.
+func RunVM(t testing.TB, source []byte, stdin []byte) Result {
+	t.Helper()
+	program, err := bytecode.Compile(Parse(t, source))
+	if err != nil {
+		t.Fatalf("bytecode: %s", err)
+	}
+	var stdout, stderr bytes.Buffer
+	code, err := bytecode.NewVM(program, bytes.NewReader(stdin), &stdout, &stderr).Run()
+	if err != nil {
+		t.Fatalf("VM: %s", err)
+	}
+	return Result{Stdout: stdout.String(), Stderr: stderr.String(), Code: code}
+}
@@ -1,5 +0,0 @@
-#include <stdio.h>
-
-int scanf(const char *format, ...);
-int getchar();
-int putchar(int);
\ No newline at end of file
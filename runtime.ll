@@ -1,183 +0,0 @@
-; ModuleID = '.\runtime.c'
-source_filename = ".\5Cruntime.c"
-target datalayout = "e-m:w-i64:64-f80:128-n8:16:32:64-S128"
-target triple = "x86_64-pc-windows-msvc19.16.27027"
-
-%struct.__crt_locale_pointers = type { %struct.__crt_locale_data*, %struct.__crt_multibyte_data* }
-%struct.__crt_locale_data = type opaque
-%struct.__crt_multibyte_data = type opaque
-
-$sprintf = comdat any
-
-$vsprintf = comdat any
-
-$_snprintf = comdat any
-
-$_vsnprintf = comdat any
-
-$_vsprintf_l = comdat any
-
-$_vsnprintf_l = comdat any
-
-$__local_stdio_printf_options = comdat any
-
-@__local_stdio_printf_options._OptionsStorage = internal global i64 0, align 8
-
-; Function Attrs: noinline nounwind optnone uwtable
-define linkonce_odr dso_local i32 @sprintf(i8*, i8*, ...) #0 comdat {
-  %3 = alloca i8*, align 8
-  %4 = alloca i8*, align 8
-  %5 = alloca i32, align 4
-  %6 = alloca i8*, align 8
-  store i8* %1, i8** %3, align 8
-  store i8* %0, i8** %4, align 8
-  %7 = bitcast i8** %6 to i8*
-  call void @llvm.va_start(i8* %7)
-  %8 = load i8*, i8** %6, align 8
-  %9 = load i8*, i8** %3, align 8
-  %10 = load i8*, i8** %4, align 8
-  %11 = call i32 @_vsprintf_l(i8* %10, i8* %9, %struct.__crt_locale_pointers* null, i8* %8)
-  store i32 %11, i32* %5, align 4
-  %12 = bitcast i8** %6 to i8*
-  call void @llvm.va_end(i8* %12)
-  %13 = load i32, i32* %5, align 4
-  ret i32 %13
-}
-
-; Function Attrs: noinline nounwind optnone uwtable
-define linkonce_odr dso_local i32 @vsprintf(i8*, i8*, i8*) #0 comdat {
-  %4 = alloca i8*, align 8
-  %5 = alloca i8*, align 8
-  %6 = alloca i8*, align 8
-  store i8* %2, i8** %4, align 8
-  store i8* %1, i8** %5, align 8
-  store i8* %0, i8** %6, align 8
-  %7 = load i8*, i8** %4, align 8
-  %8 = load i8*, i8** %5, align 8
-  %9 = load i8*, i8** %6, align 8
-  %10 = call i32 @_vsnprintf_l(i8* %9, i64 -1, i8* %8, %struct.__crt_locale_pointers* null, i8* %7)
-  ret i32 %10
-}
-
-; Function Attrs: noinline nounwind optnone uwtable
-define linkonce_odr dso_local i32 @_snprintf(i8*, i64, i8*, ...) #0 comdat {
-  %4 = alloca i8*, align 8
-  %5 = alloca i64, align 8
-  %6 = alloca i8*, align 8
-  %7 = alloca i32, align 4
-  %8 = alloca i8*, align 8
-  store i8* %2, i8** %4, align 8
-  store i64 %1, i64* %5, align 8
-  store i8* %0, i8** %6, align 8
-  %9 = bitcast i8** %8 to i8*
-  call void @llvm.va_start(i8* %9)
-  %10 = load i8*, i8** %8, align 8
-  %11 = load i8*, i8** %4, align 8
-  %12 = load i64, i64* %5, align 8
-  %13 = load i8*, i8** %6, align 8
-  %14 = call i32 @_vsnprintf(i8* %13, i64 %12, i8* %11, i8* %10)
-  store i32 %14, i32* %7, align 4
-  %15 = bitcast i8** %8 to i8*
-  call void @llvm.va_end(i8* %15)
-  %16 = load i32, i32* %7, align 4
-  ret i32 %16
-}
-
-; Function Attrs: noinline nounwind optnone uwtable
-define linkonce_odr dso_local i32 @_vsnprintf(i8*, i64, i8*, i8*) #0 comdat {
-  %5 = alloca i8*, align 8
-  %6 = alloca i8*, align 8
-  %7 = alloca i64, align 8
-  %8 = alloca i8*, align 8
-  store i8* %3, i8** %5, align 8
-  store i8* %2, i8** %6, align 8
-  store i64 %1, i64* %7, align 8
-  store i8* %0, i8** %8, align 8
-  %9 = load i8*, i8** %5, align 8
-  %10 = load i8*, i8** %6, align 8
-  %11 = load i64, i64* %7, align 8
-  %12 = load i8*, i8** %8, align 8
-  %13 = call i32 @_vsnprintf_l(i8* %12, i64 %11, i8* %10, %struct.__crt_locale_pointers* null, i8* %9)
-  ret i32 %13
-}
-
-; Function Attrs: nounwind
-declare void @llvm.va_start(i8*) #1
-
-; Function Attrs: noinline nounwind optnone uwtable
-define linkonce_odr dso_local i32 @_vsprintf_l(i8*, i8*, %struct.__crt_locale_pointers*, i8*) #0 comdat {
-  %5 = alloca i8*, align 8
-  %6 = alloca %struct.__crt_locale_pointers*, align 8
-  %7 = alloca i8*, align 8
-  %8 = alloca i8*, align 8
-  store i8* %3, i8** %5, align 8
-  store %struct.__crt_locale_pointers* %2, %struct.__crt_locale_pointers** %6, align 8
-  store i8* %1, i8** %7, align 8
-  store i8* %0, i8** %8, align 8
-  %9 = load i8*, i8** %5, align 8
-  %10 = load %struct.__crt_locale_pointers*, %struct.__crt_locale_pointers** %6, align 8
-  %11 = load i8*, i8** %7, align 8
-  %12 = load i8*, i8** %8, align 8
-  %13 = call i32 @_vsnprintf_l(i8* %12, i64 -1, i8* %11, %struct.__crt_locale_pointers* %10, i8* %9)
-  ret i32 %13
-}
-
-; Function Attrs: nounwind
-declare void @llvm.va_end(i8*) #1
-
-; Function Attrs: noinline nounwind optnone uwtable
-define linkonce_odr dso_local i32 @_vsnprintf_l(i8*, i64, i8*, %struct.__crt_locale_pointers*, i8*) #0 comdat {
-  %6 = alloca i8*, align 8
-  %7 = alloca %struct.__crt_locale_pointers*, align 8
-  %8 = alloca i8*, align 8
-  %9 = alloca i64, align 8
-  %10 = alloca i8*, align 8
-  %11 = alloca i32, align 4
-  store i8* %4, i8** %6, align 8
-  store %struct.__crt_locale_pointers* %3, %struct.__crt_locale_pointers** %7, align 8
-  store i8* %2, i8** %8, align 8
-  store i64 %1, i64* %9, align 8
-  store i8* %0, i8** %10, align 8
-  %12 = load i8*, i8** %6, align 8
-  %13 = load %struct.__crt_locale_pointers*, %struct.__crt_locale_pointers** %7, align 8
-  %14 = load i8*, i8** %8, align 8
-  %15 = load i64, i64* %9, align 8
-  %16 = load i8*, i8** %10, align 8
-  %17 = call i64* @__local_stdio_printf_options()
-  %18 = load i64, i64* %17, align 8
-  %19 = or i64 %18, 1
-  %20 = call i32 @__stdio_common_vsprintf(i64 %19, i8* %16, i64 %15, i8* %14, %struct.__crt_locale_pointers* %13, i8* %12)
-  store i32 %20, i32* %11, align 4
-  %21 = load i32, i32* %11, align 4
-  %22 = icmp slt i32 %21, 0
-  br i1 %22, label %23, label %24
-
-; <label>:23:                                     ; preds = %5
-  br label %26
-
-; <label>:24:                                     ; preds = %5
-  %25 = load i32, i32* %11, align 4
-  br label %26
-
-; <label>:26:                                     ; preds = %24, %23
-  %27 = phi i32 [ -1, %23 ], [ %25, %24 ]
-  ret i32 %27
-}
-
-declare dso_local i32 @__stdio_common_vsprintf(i64, i8*, i64, i8*, %struct.__crt_locale_pointers*, i8*) #2
-
-; Function Attrs: noinline nounwind optnone uwtable
-define linkonce_odr dso_local i64* @__local_stdio_printf_options() #0 comdat {
-  ret i64* @__local_stdio_printf_options._OptionsStorage
-}
-
-attributes #0 = { noinline nounwind optnone uwtable "correctly-rounded-divide-sqrt-fp-math"="false" "disable-tail-calls"="false" "less-precise-fpmad"="false" "min-legal-vector-width"="0" "no-frame-pointer-elim"="false" "no-infs-fp-math"="false" "no-jump-tables"="false" "no-nans-fp-math"="false" "no-signed-zeros-fp-math"="false" "no-trapping-math"="false" "stack-protector-buffer-size"="8" "target-cpu"="x86-64" "target-features"="+fxsr,+mmx,+sse,+sse2,+x87" "unsafe-fp-math"="false" "use-soft-float"="false" }
-attributes #1 = { nounwind }
-attributes #2 = { "correctly-rounded-divide-sqrt-fp-math"="false" "disable-tail-calls"="false" "less-precise-fpmad"="false" "no-frame-pointer-elim"="false" "no-infs-fp-math"="false" "no-nans-fp-math"="false" "no-signed-zeros-fp-math"="false" "no-trapping-math"="false" "stack-protector-buffer-size"="8" "target-cpu"="x86-64" "target-features"="+fxsr,+mmx,+sse,+sse2,+x87" "unsafe-fp-math"="false" "use-soft-float"="false" }
-
-!llvm.module.flags = !{!0, !1}
-!llvm.ident = !{!2}
-
-!0 = !{i32 1, !"wchar_size", i32 2}
-!1 = !{i32 7, !"PIC Level", i32 2}
-!2 = !{!"clang version 8.0.0 (tags/RELEASE_800/final)"}